@@ -4,38 +4,101 @@ import (
 	"context"
 	"errors"
 
-	"github.com/conductorone/baton-sdk/pkg/cli"
-	"github.com/spf13/cobra"
+	"github.com/conductorone/baton-sdk/pkg/field"
+	"github.com/spf13/viper"
 )
 
-// config defines the external configuration required for the connector to run.
-type config struct {
-	cli.BaseConfig `mapstructure:",squash"` // Puts the base config options in the same place as the connector options
-	Subdomain      string                   `mapstructure:"subdomain"`
-	ApiToken       string                   `mapstructure:"api-token"`
-	Email          string                   `mapstructure:"email"`
-	Orgs           []string                 `mapstructure:"orgs"`
+var (
+	subdomainField   = field.StringField("subdomain", field.WithDescription("The Zendesk subdomain."))
+	apiTokenField    = field.StringField("api-token", field.WithDescription("The Zendesk apitoken."))
+	emailField       = field.StringField("email", field.WithDescription("The Zendesk email."))
+	orgsField        = field.StringSliceField("orgs", field.WithDescription("Limit syncing to specific organizations, by name or external ID."))
+	userQueryField   = field.StringField("user-query", field.WithDescription("Zendesk search query scoping the users synced as team members, for example role:agent."))
+	orgQueryField    = field.StringField("org-query", field.WithDescription("Zendesk search query scoping the organizations synced, for example tags:managed."))
+	groupsField      = field.StringSliceField("groups", field.WithDescription("Limit syncing and provisioning to specific groups, by name or ID."))
+	groupRegexField  = field.StringField("group-name-regex", field.WithDescription("Limit syncing and provisioning to groups whose name matches this regular expression, in addition to --groups."))
+	ssoEnforcedField = field.BoolField("sso-enforced", field.WithDescription("Report team members as signing in through SSO when the account's security settings cannot be read."))
+
+	serviceAccountPatternsField        = field.StringSliceField("service-account-patterns", field.WithDescription("Regular expressions matched against user emails and names to classify service accounts."))
+	serviceAccountTagsField            = field.StringSliceField("service-account-tags", field.WithDefaultValue([]string{"service-account"}), field.WithDescription("User tags that classify service accounts."))
+	serviceAccountNeverLoggedInField   = field.BoolField("service-account-never-logged-in", field.WithDescription("Classify users that never signed in interactively and match --service-account-api-only-patterns as API-only service accounts."))
+	serviceAccountAPIOnlyPatternsField = field.StringSliceField("service-account-api-only-patterns", field.WithDefaultValue([]string{`(?i)(^|[^a-z])(api|integration|bot|no-?reply)([^a-z]|$)`}), field.WithDescription("Regular expressions matched against the emails and names of users that never signed in, to classify them as API-only."))
+
+	teamMemberRolesField       = field.StringSliceField("team-member-roles", field.WithDefaultValue([]string{"agent", "admin"}), field.WithDescription("Zendesk roles to sync as team members."))
+	excludeSuspendedField      = field.BoolField("exclude-suspended", field.WithDescription("Skip suspended team members instead of syncing them as disabled."))
+	excludeInactiveField       = field.BoolField("exclude-inactive", field.WithDescription("Skip deleted team members that Zendesk still returns, which are otherwise synced as disabled."))
+	teamMemberCustomRolesField = field.StringSliceField("team-member-custom-roles", field.WithDescription("Only sync team members assigned one of these custom role IDs."))
+
+	signOutOnRoleRestoreField = field.BoolField("sign-out-on-role-restore", field.WithDescription("Sign team members out of every session when revoking a custom role restores their previous role."))
+	syncSessionsField         = field.BoolField("sync-sessions", field.WithDescription("List the active sessions of every team member, one request each, to sync the session entitlement."))
+	defaultRoleField          = field.StringField("default-role", field.WithDescription("Role restored when revoking a custom role from a team member with no recorded previous role, as <role> or <role>:<custom role id>."))
+
+	userFieldsField         = field.StringSliceField("user-fields", field.WithDescription("Keys of custom user fields to include in team member profiles."))
+	organizationFieldsField = field.StringSliceField("organization-fields", field.WithDescription("Keys of custom organization fields to include in org descriptions."))
+
+	tagPrefixesField    = field.StringSliceField("tag-prefixes", field.WithDescription("Sync user tags starting with one of these prefixes as groups, for example access-."))
+	tagIncludeOrgsField = field.BoolField("tag-include-orgs", field.WithDescription("Include organizations carrying a synced tag as its members."))
+
+	concurrencyField       = field.IntField("concurrency", field.WithDefaultValue(4), field.WithDescription("Number of requests made in parallel to fetch group and org memberships."))
+	membershipSweepField   = field.BoolField("membership-sweep", field.WithDescription("List the group and org memberships of the whole account once per sync instead of per group and org."))
+	batchProvisioningField = field.BoolField("batch-provisioning", field.WithDescription("Create and delete group and org memberships in bulk jobs instead of one at a time. Each grant and revoke waits half a second for others to join its job, and fails if the job is not done within 10 minutes."))
+
+	recordHTTPField = field.StringField("record-http", field.WithDescription("Directory to record Zendesk API requests and responses in, without credentials or secrets."))
+	redactPIIField  = field.BoolField("record-redact-pii", field.WithDescription("Redact names, emails, tags and other personal data from recorded paths, queries and bodies."))
+	dryRunField     = field.BoolField("dry-run", field.WithDescription("Log the Zendesk API calls provisioning would make instead of making them."))
+	replayHTTPField = field.StringField("replay-http", field.WithDescription("Directory of recorded Zendesk API responses to replay instead of calling Zendesk."))
+)
+
+// configurationFields defines the external configuration required for the connector to run.
+var configurationFields = []field.SchemaField{
+	subdomainField,
+	apiTokenField,
+	emailField,
+	orgsField,
+	userQueryField,
+	orgQueryField,
+	groupsField,
+	groupRegexField,
+	ssoEnforcedField,
+	serviceAccountPatternsField,
+	serviceAccountTagsField,
+	serviceAccountNeverLoggedInField,
+	serviceAccountAPIOnlyPatternsField,
+	teamMemberRolesField,
+	excludeSuspendedField,
+	excludeInactiveField,
+	teamMemberCustomRolesField,
+	signOutOnRoleRestoreField,
+	syncSessionsField,
+	defaultRoleField,
+	userFieldsField,
+	organizationFieldsField,
+	tagPrefixesField,
+	tagIncludeOrgsField,
+	recordHTTPField,
+	redactPIIField,
+	replayHTTPField,
+	concurrencyField,
+	membershipSweepField,
+	batchProvisioningField,
+	dryRunField,
 }
 
 // validateConfig is run after the configuration is loaded, and should return an error if it isn't valid.
-func validateConfig(ctx context.Context, cfg *config) error {
-	if cfg.Subdomain == "" {
+func validateConfig(ctx context.Context, v *viper.Viper) error {
+	if v.GetString(subdomainField.FieldName) == "" {
 		return errors.New("subdomain is required")
 	}
-	if cfg.ApiToken == "" {
+	// Replayed syncs never reach Zendesk, so they need no credentials.
+	if v.GetString(replayHTTPField.FieldName) != "" {
+		return nil
+	}
+	if v.GetString(apiTokenField.FieldName) == "" {
 		return errors.New("api-Token is required")
 	}
-	if cfg.Email == "" {
+	if v.GetString(emailField.FieldName) == "" {
 		return errors.New("email is required")
 	}
 
 	return nil
 }
-
-// cmdFlags sets the cmdFlags required for the connector.
-func cmdFlags(cmd *cobra.Command) {
-	cmd.PersistentFlags().String("subdomain", "", "The Zendesk subdomain. ($BATON_SUBDOMAIN)")
-	cmd.PersistentFlags().String("api-token", "", "The Zendesk apitoken. ($BATON_API_TOKEN)")
-	cmd.PersistentFlags().String("email", "", "The Zendesk email. ($BATON_EMAIL)")
-	cmd.PersistentFlags().StringSlice("orgs", []string{}, "Limit syncing to specific organizations. ($BATON_ORGS)")
-}
@@ -5,10 +5,12 @@ import (
 	"fmt"
 	"os"
 
-	"github.com/conductorone/baton-sdk/pkg/cli"
+	configSchema "github.com/conductorone/baton-sdk/pkg/config"
 	"github.com/conductorone/baton-sdk/pkg/connectorbuilder"
+	"github.com/conductorone/baton-sdk/pkg/field"
 	"github.com/conductorone/baton-sdk/pkg/types"
 	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"github.com/spf13/viper"
 	"go.uber.org/zap"
 
 	"github.com/conductorone/baton-zendesk/pkg/connector"
@@ -19,15 +21,13 @@ var version = "dev"
 func main() {
 	ctx := context.Background()
 
-	cfg := &config{}
-	cmd, err := cli.NewCmd(ctx, "baton-zendesk", cfg, validateConfig, getConnector)
+	_, cmd, err := configSchema.DefineConfiguration(ctx, "baton-zendesk", getConnector, field.NewConfiguration(configurationFields))
 	if err != nil {
 		fmt.Fprintln(os.Stderr, err.Error())
 		os.Exit(1)
 	}
 
 	cmd.Version = version
-	cmdFlags(cmd)
 
 	err = cmd.Execute()
 	if err != nil {
@@ -36,10 +36,46 @@ func main() {
 	}
 }
 
-func getConnector(ctx context.Context, cfg *config) (types.ConnectorServer, error) {
+func getConnector(ctx context.Context, v *viper.Viper) (types.ConnectorServer, error) {
 	l := ctxzap.Extract(ctx)
 
-	cb, err := connector.New(ctx, cfg.Orgs, cfg.Subdomain, cfg.Email, cfg.ApiToken)
+	if err := validateConfig(ctx, v); err != nil {
+		return nil, err
+	}
+
+	cb, err := connector.New(ctx, &connector.Config{
+		Subdomain:                     v.GetString(subdomainField.FieldName),
+		Email:                         v.GetString(emailField.FieldName),
+		ApiToken:                      v.GetString(apiTokenField.FieldName),
+		Orgs:                          v.GetStringSlice(orgsField.FieldName),
+		UserQuery:                     v.GetString(userQueryField.FieldName),
+		OrgQuery:                      v.GetString(orgQueryField.FieldName),
+		Groups:                        v.GetStringSlice(groupsField.FieldName),
+		GroupNameRegex:                v.GetString(groupRegexField.FieldName),
+		SSOEnforced:                   v.GetBool(ssoEnforcedField.FieldName),
+		ServiceAccountPatterns:        v.GetStringSlice(serviceAccountPatternsField.FieldName),
+		ServiceAccountTags:            v.GetStringSlice(serviceAccountTagsField.FieldName),
+		ServiceAccountNeverLoggedIn:   v.GetBool(serviceAccountNeverLoggedInField.FieldName),
+		ServiceAccountAPIOnlyPatterns: v.GetStringSlice(serviceAccountAPIOnlyPatternsField.FieldName),
+		TeamMemberRoles:               v.GetStringSlice(teamMemberRolesField.FieldName),
+		ExcludeSuspended:              v.GetBool(excludeSuspendedField.FieldName),
+		ExcludeInactive:               v.GetBool(excludeInactiveField.FieldName),
+		TeamMemberCustomRoles:         v.GetStringSlice(teamMemberCustomRolesField.FieldName),
+		SignOutOnRoleRestore:          v.GetBool(signOutOnRoleRestoreField.FieldName),
+		SyncSessions:                  v.GetBool(syncSessionsField.FieldName),
+		DefaultRole:                   v.GetString(defaultRoleField.FieldName),
+		UserFields:                    v.GetStringSlice(userFieldsField.FieldName),
+		OrganizationFields:            v.GetStringSlice(organizationFieldsField.FieldName),
+		TagPrefixes:                   v.GetStringSlice(tagPrefixesField.FieldName),
+		TagIncludeOrgs:                v.GetBool(tagIncludeOrgsField.FieldName),
+		RecordHTTPDir:                 v.GetString(recordHTTPField.FieldName),
+		RecordRedactPII:               v.GetBool(redactPIIField.FieldName),
+		ReplayHTTPDir:                 v.GetString(replayHTTPField.FieldName),
+		Concurrency:                   v.GetInt(concurrencyField.FieldName),
+		MembershipSweep:               v.GetBool(membershipSweepField.FieldName),
+		BatchProvisioning:             v.GetBool(batchProvisioningField.FieldName),
+		DryRun:                        v.GetBool(dryRunField.FieldName),
+	})
 	if err != nil {
 		l.Error("error creating connector", zap.Error(err))
 		return nil, err
@@ -0,0 +1,47 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+)
+
+// ErrSecuritySettingsUnavailable is returned when the settings of the account do not include its sign-in settings,
+// as on plans that do not offer single sign-on.
+var ErrSecuritySettingsUnavailable = errors.New("zendesk-connector: account security settings are unavailable")
+
+// AccountSecuritySettings are the sign-in settings of the account.
+type AccountSecuritySettings struct {
+	// AgentSSO reports whether team members sign in through single sign-on instead of a Zendesk password.
+	AgentSSO bool
+}
+
+// GetAccountSecuritySettings returns the sign-in settings of the account.
+//
+// Zendesk API docs: https://developer.zendesk.com/api-reference/ticketing/account-configuration/account_settings/#show-settings
+func (z *ZendeskClient) GetAccountSecuritySettings(ctx context.Context) (AccountSecuritySettings, error) {
+	var result struct {
+		Settings struct {
+			Security *struct {
+				AgentSSO *bool `json:"agent_sso_enabled"`
+			} `json:"security"`
+		} `json:"settings"`
+	}
+
+	body, err := z.client.Get(ctx, "/account/settings.json")
+	if err != nil {
+		return AccountSecuritySettings{}, err
+	}
+
+	err = json.Unmarshal(body, &result)
+	if err != nil {
+		return AccountSecuritySettings{}, err
+	}
+
+	security := result.Settings.Security
+	if security == nil || security.AgentSSO == nil {
+		return AccountSecuritySettings{}, ErrSecuritySettingsUnavailable
+	}
+
+	return AccountSecuritySettings{AgentSSO: *security.AgentSSO}, nil
+}
@@ -0,0 +1,136 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/nukosuke/go-zendesk/zendesk"
+)
+
+// zendeskAPI is the go-zendesk client with the errors it reports for responses with an error status turned into
+// an APIError. go-zendesk only reports the status and raw body of those responses.
+type zendeskAPI struct {
+	client *zendesk.Client
+}
+
+// apiError returns err as an APIError if go-zendesk reported it for a response with an error status.
+func apiError(err error) error {
+	var zerr zendesk.Error
+	if !errors.As(err, &zerr) || zerr.Status() < http.StatusBadRequest {
+		return err
+	}
+
+	body, readErr := io.ReadAll(zerr.Body())
+	if readErr != nil {
+		return err
+	}
+
+	return newAPIError(zerr.Status(), zerr.Headers(), body)
+}
+
+func (a *zendeskAPI) Get(ctx context.Context, path string) ([]byte, error) {
+	body, err := a.client.Get(ctx, path)
+	return body, apiError(err)
+}
+
+func (a *zendeskAPI) Post(ctx context.Context, path string, data interface{}) ([]byte, error) {
+	body, err := a.client.Post(ctx, path, data)
+	return body, apiError(err)
+}
+
+func (a *zendeskAPI) Put(ctx context.Context, path string, data interface{}) ([]byte, error) {
+	body, err := a.client.Put(ctx, path, data)
+	return body, apiError(err)
+}
+
+// Delete sends a DELETE request. Zendesk answers some of them with 200 OK and a body rather than 204 No Content,
+// which go-zendesk reports as an error.
+func (a *zendeskAPI) Delete(ctx context.Context, path string) error {
+	_, err := a.DeleteWithResponse(ctx, path)
+	return err
+}
+
+// DeleteWithResponse sends a DELETE request and returns the body of the response, if any.
+func (a *zendeskAPI) DeleteWithResponse(ctx context.Context, path string) ([]byte, error) {
+	err := a.client.Delete(ctx, path)
+
+	var zerr zendesk.Error
+	if errors.As(err, &zerr) && zerr.Status() == http.StatusOK {
+		body := zerr.Body()
+		defer body.Close()
+
+		return io.ReadAll(body)
+	}
+
+	return nil, apiError(err)
+}
+
+func (a *zendeskAPI) GetCustomRoles(ctx context.Context) ([]zendesk.CustomRole, error) {
+	roles, err := a.client.GetCustomRoles(ctx)
+	return roles, apiError(err)
+}
+
+func (a *zendeskAPI) GetGroup(ctx context.Context, groupID int64) (zendesk.Group, error) {
+	group, err := a.client.GetGroup(ctx, groupID)
+	return group, apiError(err)
+}
+
+func (a *zendeskAPI) GetGroups(ctx context.Context, opts *zendesk.GroupListOptions) ([]zendesk.Group, zendesk.Page, error) {
+	groups, page, err := a.client.GetGroups(ctx, opts)
+	return groups, page, apiError(err)
+}
+
+func (a *zendeskAPI) GetManyUsers(ctx context.Context, opts *zendesk.GetManyUsersOptions) ([]zendesk.User, zendesk.Page, error) {
+	users, page, err := a.client.GetManyUsers(ctx, opts)
+	return users, page, apiError(err)
+}
+
+func (a *zendeskAPI) GetOrganization(ctx context.Context, orgID int64) (zendesk.Organization, error) {
+	org, err := a.client.GetOrganization(ctx, orgID)
+	return org, apiError(err)
+}
+
+func (a *zendeskAPI) GetOrganizationMemberships(
+	ctx context.Context,
+	opts *zendesk.OrganizationMembershipListOptions,
+) ([]zendesk.OrganizationMembership, zendesk.Page, error) {
+	memberships, page, err := a.client.GetOrganizationMemberships(ctx, opts)
+	return memberships, page, apiError(err)
+}
+
+func (a *zendeskAPI) GetOrganizationUsers(ctx context.Context, orgID int64, opts *zendesk.UserListOptions) ([]zendesk.User, zendesk.Page, error) {
+	users, page, err := a.client.GetOrganizationUsers(ctx, orgID, opts)
+	return users, page, apiError(err)
+}
+
+func (a *zendeskAPI) GetOrganizations(ctx context.Context, opts *zendesk.OrganizationListOptions) ([]zendesk.Organization, zendesk.Page, error) {
+	orgs, page, err := a.client.GetOrganizations(ctx, opts)
+	return orgs, page, apiError(err)
+}
+
+func (a *zendeskAPI) GetUser(ctx context.Context, userID int64) (zendesk.User, error) {
+	user, err := a.client.GetUser(ctx, userID)
+	return user, apiError(err)
+}
+
+func (a *zendeskAPI) GetUserFields(ctx context.Context, opts *zendesk.UserFieldListOptions) ([]zendesk.UserField, zendesk.Page, error) {
+	fields, page, err := a.client.GetUserFields(ctx, opts)
+	return fields, page, apiError(err)
+}
+
+func (a *zendeskAPI) GetUsers(ctx context.Context, opts *zendesk.UserListOptions) ([]zendesk.User, zendesk.Page, error) {
+	users, page, err := a.client.GetUsers(ctx, opts)
+	return users, page, apiError(err)
+}
+
+func (a *zendeskAPI) GetWebhookSigningSecret(ctx context.Context, webhookID string) (*zendesk.WebhookSigningSecret, error) {
+	secret, err := a.client.GetWebhookSigningSecret(ctx, webhookID)
+	return secret, apiError(err)
+}
+
+func (a *zendeskAPI) SearchUsers(ctx context.Context, opts *zendesk.SearchUsersOptions) ([]zendesk.User, zendesk.Page, error) {
+	users, page, err := a.client.SearchUsers(ctx, opts)
+	return users, page, apiError(err)
+}
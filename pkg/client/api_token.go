@@ -0,0 +1,130 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/nukosuke/go-zendesk/zendesk"
+)
+
+// APIToken is a Zendesk API token. Token only holds the full value right
+// after creation, listings return a masked value.
+type APIToken struct {
+	ID          int64     `json:"id,omitempty"`
+	URL         string    `json:"url,omitempty"`
+	Token       string    `json:"token,omitempty"`
+	Description string    `json:"description,omitempty"`
+	Active      bool      `json:"active,omitempty"`
+	CreatedAt   time.Time `json:"created_at,omitempty"`
+	LastUsedAt  time.Time `json:"last_used_at,omitempty"`
+}
+
+// ListAPITokens returns the API tokens of the account.
+//
+// Zendesk API docs: https://developer.zendesk.com/api-reference/ticketing/account-configuration/api_tokens/
+func (z *ZendeskClient) ListAPITokens(ctx context.Context, pageToken int) ([]APIToken, string, error) {
+	var (
+		nextPageToken string
+		result        struct {
+			APITokens []APIToken `json:"api_tokens"`
+			zendesk.Page
+		}
+	)
+
+	body, err := z.client.Get(ctx, withPage("/api_tokens.json", pageToken))
+	if err != nil {
+		return nil, "", err
+	}
+
+	err = json.Unmarshal(body, &result)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if result.NextPage != nil {
+		nextPageToken, err = parseNextPage(*result.NextPage)
+		if err != nil {
+			return nil, "", err
+		}
+	}
+
+	return result.APITokens, nextPageToken, nil
+}
+
+// GetAPIToken gets an existing API token.
+func (z *ZendeskClient) GetAPIToken(ctx context.Context, tokenID int64) (APIToken, error) {
+	var result struct {
+		APIToken APIToken `json:"api_token"`
+	}
+
+	body, err := z.client.Get(ctx, fmt.Sprintf("/api_tokens/%d.json", tokenID))
+	if err != nil {
+		return APIToken{}, err
+	}
+
+	err = json.Unmarshal(body, &result)
+	if err != nil {
+		return APIToken{}, err
+	}
+
+	return result.APIToken, nil
+}
+
+// CreateAPIToken creates a new API token. The returned token carries the full value.
+func (z *ZendeskClient) CreateAPIToken(ctx context.Context, description string) (APIToken, error) {
+	var data, result struct {
+		APIToken APIToken `json:"api_token"`
+	}
+
+	data.APIToken = APIToken{Description: description}
+	body, err := z.client.Post(ctx, "/api_tokens.json", data)
+	if err != nil {
+		return APIToken{}, err
+	}
+
+	err = json.Unmarshal(body, &result)
+	if err != nil {
+		return APIToken{}, err
+	}
+
+	return result.APIToken, nil
+}
+
+// DeleteAPIToken deletes an API token.
+func (z *ZendeskClient) DeleteAPIToken(ctx context.Context, tokenID int64) error {
+	return z.client.Delete(ctx, fmt.Sprintf("/api_tokens/%d.json", tokenID))
+}
+
+// IsCurrentAPIToken reports whether token is, or may be, the one the client authenticates with.
+// Listed tokens are masked, so only the visible leading and trailing characters are compared. A token whose
+// masking leaves too little to compare counts as current, so that it is never mistaken for another token.
+func (z *ZendeskClient) IsCurrentAPIToken(token APIToken) bool {
+	if z.apiToken == "" {
+		return false
+	}
+	if token.Token == z.apiToken {
+		return true
+	}
+
+	isMask := func(r rune) bool {
+		return r == '*' || r == '.' || r == '…'
+	}
+	first := strings.IndexFunc(token.Token, isMask)
+	last := strings.LastIndexFunc(token.Token, isMask)
+	if first == -1 {
+		return true
+	}
+
+	_, size := utf8.DecodeRuneInString(token.Token[last:])
+	head := token.Token[:first]
+	tail := token.Token[last+size:]
+	if len(head)+len(tail) < 4 {
+		return true
+	}
+
+	return strings.HasPrefix(z.apiToken, head) && strings.HasSuffix(z.apiToken, tail)
+}
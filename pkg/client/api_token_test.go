@@ -0,0 +1,30 @@
+package client
+
+import "testing"
+
+func TestIsCurrentAPIToken(t *testing.T) {
+	z := &ZendeskClient{apiToken: "abcdef123456"}
+
+	for _, tc := range []struct {
+		token string
+		want  bool
+	}{
+		{"abcdef123456", true},
+		{"abc****456", true},
+		{"abc****789", false},
+		{"xyz…456", false},
+		// Tokens masked so much that they cannot be compared may be the connector's own.
+		{"a****6", true},
+		{"********", true},
+		{"", true},
+		{"unmasked", true},
+	} {
+		if got := z.IsCurrentAPIToken(APIToken{Token: tc.token}); got != tc.want {
+			t.Errorf("IsCurrentAPIToken(%q) = %t, want %t", tc.token, got, tc.want)
+		}
+	}
+
+	if (&ZendeskClient{}).IsCurrentAPIToken(APIToken{Token: "abc****456"}) {
+		t.Errorf("a client authenticating without an api token reports a token as current")
+	}
+}
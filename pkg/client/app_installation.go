@@ -0,0 +1,85 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/nukosuke/go-zendesk/zendesk"
+)
+
+// AppInstallation is an installed Zendesk app along with the roles and groups it is restricted to.
+// go-zendesk does not decode the restrictions, so they are added here.
+type AppInstallation struct {
+	zendesk.AppInstallation
+	RoleRestrictions  []int64 `json:"role_restrictions"`
+	GroupRestrictions []int64 `json:"group_restrictions"`
+}
+
+// ListAppInstallations returns all apps installed in the account.
+//
+// Zendesk API docs: https://developer.zendesk.com/api-reference/ticketing/apps/apps/#list-app-installations
+func (z *ZendeskClient) ListAppInstallations(ctx context.Context) ([]AppInstallation, error) {
+	var result struct {
+		Installations []AppInstallation `json:"installations"`
+	}
+
+	body, err := z.client.Get(ctx, "/apps/installations.json")
+	if err != nil {
+		return nil, err
+	}
+
+	err = json.Unmarshal(body, &result)
+	if err != nil {
+		return nil, err
+	}
+
+	return result.Installations, nil
+}
+
+// GetAppInstallation gets an existing app installation.
+//
+// Zendesk API docs: https://developer.zendesk.com/api-reference/ticketing/apps/apps/#show-app-installation
+func (z *ZendeskClient) GetAppInstallation(ctx context.Context, installationID int64) (AppInstallation, error) {
+	var result AppInstallation
+
+	body, err := z.client.Get(ctx, fmt.Sprintf("/apps/installations/%d.json", installationID))
+	if err != nil {
+		return AppInstallation{}, err
+	}
+
+	err = json.Unmarshal(body, &result)
+	if err != nil {
+		return AppInstallation{}, err
+	}
+
+	return result, nil
+}
+
+// UpdateAppInstallationRestrictions replaces the role and group restrictions of an app installation.
+//
+// Zendesk API docs: https://developer.zendesk.com/api-reference/ticketing/apps/apps/#update-app-installation
+func (z *ZendeskClient) UpdateAppInstallationRestrictions(ctx context.Context, installationID int64, roleRestrictions []int64, groupRestrictions []int64) (AppInstallation, error) {
+	var (
+		result AppInstallation
+		data   = struct {
+			RoleRestrictions  []int64 `json:"role_restrictions"`
+			GroupRestrictions []int64 `json:"group_restrictions"`
+		}{
+			RoleRestrictions:  roleRestrictions,
+			GroupRestrictions: groupRestrictions,
+		}
+	)
+
+	body, err := z.client.Put(ctx, fmt.Sprintf("/apps/installations/%d.json", installationID), data)
+	if err != nil {
+		return AppInstallation{}, err
+	}
+
+	err = json.Unmarshal(body, &result)
+	if err != nil {
+		return AppInstallation{}, err
+	}
+
+	return result, nil
+}
@@ -16,12 +16,35 @@ import (
 )
 
 type ZendeskClient struct {
-	client *zendesk.Client
+	client   *zendeskAPI
+	apiToken string
 }
 
-func New(ctx context.Context, httpClient *http.Client, subdomain string, email string, apiToken string) (*ZendeskClient, error) {
+// Option configures a ZendeskClient.
+type Option func(*zendesk.Client) error
+
+// WithEndpointURL sends every request to the given API endpoint instead of the subdomain,
+// for example a fake Zendesk server in tests. The endpoint must include the /api/v2 path.
+func WithEndpointURL(endpoint string) Option {
+	return func(c *zendesk.Client) error {
+		return c.SetEndpointURL(endpoint)
+	}
+}
+
+// New returns a client of the Zendesk account. Errors reported by Zendesk are returned as an APIError.
+func New(ctx context.Context, httpClient *http.Client, subdomain string, email string, apiToken string, opts ...Option) (*ZendeskClient, error) {
 	zc := &ZendeskClient{}
-	client, err := zendesk.NewClient(httpClient)
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	transport := httpClient.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	withRequestBodies := *httpClient
+	withRequestBodies.Transport = newRequestBodyTransport(transport)
+
+	client, err := zendesk.NewClient(&withRequestBodies)
 	if err != nil {
 		return nil, err
 	}
@@ -29,8 +52,15 @@ func New(ctx context.Context, httpClient *http.Client, subdomain string, email s
 	if err != nil {
 		return nil, err
 	}
+	for _, opt := range opts {
+		err = opt(client)
+		if err != nil {
+			return nil, err
+		}
+	}
 	client.SetCredential(zendesk.NewAPITokenCredential(email, apiToken))
-	zc.client = client
+	zc.client = &zendeskAPI{client: client}
+	zc.apiToken = apiToken
 	return zc, nil
 }
 
@@ -81,7 +111,7 @@ func (z *ZendeskClient) ListGroups(ctx context.Context, pageToken int) ([]zendes
 // ListOrganizations fetch organization list.
 func (z *ZendeskClient) ListOrganizations(ctx context.Context, opts *zendesk.OrganizationListOptions) ([]zendesk.Organization, string, error) {
 	var nextPageToken string
-	orgs, page, err := z.client.GetOrganizations(ctx, &zendesk.OrganizationListOptions{})
+	orgs, page, err := z.client.GetOrganizations(ctx, opts)
 	if err != nil {
 		return nil, "", fmt.Errorf("zendesk-connector: failed to fetch org: %w", err)
 	}
@@ -96,23 +126,36 @@ func (z *ZendeskClient) ListOrganizations(ctx context.Context, opts *zendesk.Org
 	return orgs, nextPageToken, err
 }
 
-// GetGroupMemberships get the memberships of the specified group.
-func (z *ZendeskClient) GetGroupMemberships(ctx context.Context, groupId int64) ([]zendesk.GroupMembership, string, error) {
-	var nextPageToken string
-	groupMemberships, page, err := z.client.GetGroupMemberships(ctx, &zendesk.GroupMembershipListOptions{
-		GroupID: groupId,
-	})
+// GetGroupMemberships get a page of the memberships of the specified group.
+//
+// Zendesk API docs: https://developer.zendesk.com/api-reference/ticketing/groups/group_memberships/#list-memberships
+func (z *ZendeskClient) GetGroupMemberships(ctx context.Context, groupId int64, pageToken int) ([]zendesk.GroupMembership, string, error) {
+	var (
+		nextPageToken string
+		result        struct {
+			GroupMemberships []zendesk.GroupMembership `json:"group_memberships"`
+			zendesk.Page
+		}
+	)
+
+	body, err := z.client.Get(ctx, withPage(fmt.Sprintf("/groups/%d/memberships.json", groupId), pageToken))
 	if err != nil {
 		return nil, "", err
 	}
-	if page.NextPage != nil {
-		nextPageToken, err = parseNextPage(*page.NextPage)
+
+	err = json.Unmarshal(body, &result)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if result.NextPage != nil {
+		nextPageToken, err = parseNextPage(*result.NextPage)
 		if err != nil {
 			return nil, "", err
 		}
 	}
 
-	return groupMemberships, nextPageToken, err
+	return result.GroupMemberships, nextPageToken, nil
 }
 
 // GetUser get an existing user.
@@ -150,6 +193,25 @@ func (z *ZendeskClient) GetUsers(ctx context.Context, opts *zendesk.UserListOpti
 	return mapUsers, nextPageToken, err
 }
 
+// GetManyUsers returns the users with the given IDs, up to 100 at once. Users that no longer exist are left out.
+//
+// Zendesk API docs: https://developer.zendesk.com/api-reference/ticketing/users/users/#show-many-users
+func (z *ZendeskClient) GetManyUsers(ctx context.Context, userIDs []int64) ([]zendesk.User, error) {
+	ids := make([]string, 0, len(userIDs))
+	for _, userID := range userIDs {
+		ids = append(ids, strconv.FormatInt(userID, 10))
+	}
+
+	users, _, err := z.client.GetManyUsers(ctx, &zendesk.GetManyUsersOptions{
+		IDs: strings.Join(ids, ","),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return users, nil
+}
+
 // GetGroupDetails get an existing group.
 func (z *ZendeskClient) GetGroupDetails(ctx context.Context, groupID int64) (zendesk.Group, error) {
 	group, err := z.client.GetGroup(ctx, groupID)
@@ -183,7 +245,7 @@ func (z *ZendeskClient) GetOrganizationUsers(ctx context.Context, orgID *v2.Reso
 		return nil, "", err
 	}
 
-	users, page, err := z.client.GetOrganizationUsers(ctx, oID, nil)
+	users, page, err := z.client.GetOrganizationUsers(ctx, oID, opts)
 
 	if err != nil {
 		return nil, "", err
@@ -319,42 +381,41 @@ func (z *ZendeskClient) CreateCustomRoleMembership(ctx context.Context, roleMemb
 	return result.CustomRoles, nil
 }
 
-// GetGroupMembershipByGroup gets an existing group membership.
+// ErrMembershipNotFound is returned when a user is not a member of the group or organization looked up.
+var ErrMembershipNotFound = errors.New("zendesk-connector: membership not found")
+
+// GetGroupMembershipByGroup gets an existing group membership, reading every page of the user's group memberships
+// before reporting it missing.
 func (z *ZendeskClient) GetGroupMembershipByGroup(ctx context.Context, groupMemberships zendesk.GroupMembership) (string, zendesk.Page, error) {
-	groups, nextPage, err := z.client.GetGroupMemberships(ctx, &zendesk.GroupMembershipListOptions{
-		UserID:  groupMemberships.UserID,
-		GroupID: groupMemberships.GroupID,
-	})
+	memberships, err := z.UserGroupMemberships(ctx, groupMemberships.UserID)
 	if err != nil {
 		return "", zendesk.Page{}, fmt.Errorf("zendesk-connector: failed to fetch groupmembership: %w", err)
 	}
 
-	for _, group := range groups {
-		if groupMemberships.UserID == group.UserID {
-			return fmt.Sprintf("%d", group.ID), nextPage, nil
+	for _, membership := range memberships {
+		if membership.GroupID == groupMemberships.GroupID {
+			return fmt.Sprintf("%d", membership.ID), zendesk.Page{}, nil
 		}
 	}
 
-	return "", zendesk.Page{}, err
+	return "", zendesk.Page{}, ErrMembershipNotFound
 }
 
-// GetOrganizationMembershipByUser gets an existing organization membership.
+// GetOrganizationMembershipByUser gets an existing organization membership, reading every page of the user's
+// organization memberships before reporting it missing.
 func (z *ZendeskClient) GetOrganizationMembershipByUser(ctx context.Context, organizationMemberships zendesk.OrganizationMembershipListOptions) (string, zendesk.Page, error) {
-	organizations, nextPage, err := z.client.GetOrganizationMemberships(ctx, &zendesk.OrganizationMembershipListOptions{
-		UserID:         organizationMemberships.UserID,
-		OrganizationID: organizationMemberships.OrganizationID,
-	})
+	memberships, err := z.UserOrganizationMemberships(ctx, organizationMemberships.UserID)
 	if err != nil {
 		return "", zendesk.Page{}, fmt.Errorf("zendesk-connector: failed to fetch organizationmemberships: %w", err)
 	}
 
-	for _, organization := range organizations {
-		if organizationMemberships.UserID == organization.UserID {
-			return fmt.Sprintf("%d", organization.ID), nextPage, nil
+	for _, membership := range memberships {
+		if membership.OrganizationID == organizationMemberships.OrganizationID {
+			return fmt.Sprintf("%d", membership.ID), zendesk.Page{}, nil
 		}
 	}
 
-	return "", zendesk.Page{}, err
+	return "", zendesk.Page{}, ErrMembershipNotFound
 }
 
 // RemoveGroupMembershipByID removes a user from a group, given a specified
@@ -366,7 +427,12 @@ func (z *ZendeskClient) RemoveGroupMembershipByID(ctx context.Context, groupMemb
 		return "", err
 	}
 
-	err = z.client.Delete(ctx, fmt.Sprintf("/group_memberships/%s", groupMembershipID))
+	id, err := strconv.ParseInt(groupMembershipID, 10, 64)
+	if err != nil {
+		return "", err
+	}
+
+	err = z.DeleteGroupMembership(ctx, id)
 	if err != nil {
 		return "", err
 	}
@@ -383,7 +449,12 @@ func (z *ZendeskClient) RemoveOrganizationMembershipByID(ctx context.Context, or
 		return "", err
 	}
 
-	err = z.client.Delete(ctx, fmt.Sprintf("/organization_memberships/%s", organizationMembershipID))
+	id, err := strconv.ParseInt(organizationMembershipID, 10, 64)
+	if err != nil {
+		return "", err
+	}
+
+	err = z.DeleteOrganizationMembership(ctx, id)
 	if err != nil {
 		return "", err
 	}
@@ -404,6 +475,20 @@ func parseNextPage(u string) (string, error) {
 	return nextPageToken, nil
 }
 
+// withPage adds the page query parameter to an offset paginated path.
+func withPage(path string, pageToken int) string {
+	if pageToken == 0 {
+		return path
+	}
+
+	sep := "?"
+	if strings.Contains(path, "?") {
+		sep = "&"
+	}
+
+	return fmt.Sprintf("%s%spage=%d", path, sep, pageToken)
+}
+
 // CreateOrganizationMembership creates an organization membership for an existing user and org
 // https://developer.zendesk.com/api-reference/ticketing/organizations/organization_memberships/#create-membership
 func (z *ZendeskClient) CreateOrganizationMembership(ctx context.Context, opts zendesk.OrganizationMembership) (zendesk.OrganizationMembership, error) {
@@ -0,0 +1,82 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+
+	"github.com/nukosuke/go-zendesk/zendesk"
+)
+
+// ListUserFields returns the definitions of all custom user fields.
+//
+// Zendesk API docs: https://developer.zendesk.com/api-reference/ticketing/users/user_fields/#list-user-fields
+func (z *ZendeskClient) ListUserFields(ctx context.Context) ([]zendesk.UserField, error) {
+	var (
+		fields []zendesk.UserField
+		opts   = &zendesk.UserFieldListOptions{}
+	)
+
+	for {
+		page, p, err := z.client.GetUserFields(ctx, opts)
+		if err != nil {
+			return nil, err
+		}
+
+		fields = append(fields, page...)
+		if p.NextPage == nil {
+			return fields, nil
+		}
+
+		nextPageToken, err := parseNextPage(*p.NextPage)
+		if err != nil {
+			return nil, err
+		}
+		opts.Page, err = strconv.Atoi(nextPageToken)
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// ListOrganizationFields returns the definitions of all custom organization fields.
+//
+// Zendesk API docs: https://developer.zendesk.com/api-reference/ticketing/organizations/organization_fields/#list-organization-fields
+func (z *ZendeskClient) ListOrganizationFields(ctx context.Context) ([]zendesk.OrganizationField, error) {
+	var (
+		fields    []zendesk.OrganizationField
+		pageToken int
+	)
+
+	// go-zendesk only reads the first page of organization fields.
+	for {
+		var result struct {
+			OrganizationFields []zendesk.OrganizationField `json:"organization_fields"`
+			zendesk.Page
+		}
+
+		body, err := z.client.Get(ctx, withPage("/organization_fields.json", pageToken))
+		if err != nil {
+			return nil, err
+		}
+
+		err = json.Unmarshal(body, &result)
+		if err != nil {
+			return nil, err
+		}
+
+		fields = append(fields, result.OrganizationFields...)
+		if result.NextPage == nil {
+			return fields, nil
+		}
+
+		nextPageToken, err := parseNextPage(*result.NextPage)
+		if err != nil {
+			return nil, err
+		}
+		pageToken, err = strconv.Atoi(nextPageToken)
+		if err != nil {
+			return nil, err
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
+)
+
+type dryRunTransport struct {
+	next http.RoundTripper
+}
+
+// NewDryRunTransport returns a transport that sends read requests, so IDs and preconditions are resolved as usual,
+// but only logs the requests that would change anything and answers them with a synthetic success.
+// Creates and updates are answered with the request body, so callers can decode the object they sent,
+// and bulk requests with a completed background job.
+func NewDryRunTransport(next http.RoundTripper) http.RoundTripper {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+
+	return &dryRunTransport{
+		next: next,
+	}
+}
+
+func (t *dryRunTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method == http.MethodGet || req.Method == http.MethodHead {
+		return t.next.RoundTrip(req)
+	}
+
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	ctxzap.Extract(req.Context()).Info("dry run: skipped request",
+		zap.String("method", req.Method),
+		zap.String("path", requestPath(req)),
+		zap.ByteString("body", body),
+	)
+
+	status := http.StatusOK
+	switch {
+	case isBulkRequest(req):
+		// Bulk requests answer with a background job, reported as already completed along with every item sent.
+		var err error
+		body, err = dryRunJobStatus(req, body)
+		if err != nil {
+			return nil, err
+		}
+	case req.Method == http.MethodPost:
+		status = http.StatusCreated
+	case req.Method == http.MethodDelete:
+		status = http.StatusNoContent
+		body = nil
+	}
+
+	header := make(http.Header)
+	if len(body) > 0 {
+		header.Set("Content-Type", "application/json")
+	}
+
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", status, http.StatusText(status)),
+		StatusCode:    status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}, nil
+}
+
+func isBulkRequest(req *http.Request) bool {
+	for _, suffix := range []string{"/create_many.json", "/update_many.json", "/destroy_many.json", "/create_or_update_many.json"} {
+		if strings.HasSuffix(req.URL.Path, suffix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// dryRunJobStatus returns a completed background job reporting the success of every item of a bulk request.
+// Items of bulk deletes are sent as IDs in the query and reported by ID, and the others by their index in the body.
+func dryRunJobStatus(req *http.Request, body []byte) ([]byte, error) {
+	results := []JobResult{}
+
+	if ids := req.URL.Query().Get("ids"); ids != "" {
+		for _, value := range strings.Split(ids, ",") {
+			id, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("zendesk-connector: invalid id %q in dry run bulk request: %w", value, err)
+			}
+			results = append(results, JobResult{ID: id, Success: true, Action: "delete"})
+		}
+	} else if len(body) > 0 {
+		var request map[string]json.RawMessage
+		err := json.Unmarshal(body, &request)
+		if err != nil {
+			return nil, fmt.Errorf("zendesk-connector: failed to decode dry run bulk request: %w", err)
+		}
+
+		for _, raw := range request {
+			var items []json.RawMessage
+			if json.Unmarshal(raw, &items) != nil {
+				continue
+			}
+			for i := range items {
+				index := i
+				results = append(results, JobResult{Index: &index, Success: true, Action: "create"})
+			}
+		}
+	}
+
+	return json.Marshal(map[string]interface{}{
+		"job_status": JobStatus{
+			ID:      "dry-run",
+			Status:  JobStatusCompleted,
+			Total:   len(results),
+			Results: results,
+		},
+	})
+}
@@ -0,0 +1,148 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// defaultRetryAfter is how long to wait after a rate limited request that did not say when to retry.
+const defaultRetryAfter = time.Minute
+
+// APIError is a request Zendesk answered with an error status, along with the error it reported in the body.
+// It converts to a gRPC status, so that callers can tell bad credentials apart from a transient outage.
+type APIError struct {
+	StatusCode int
+	// Code is the kind of error Zendesk reported, for example RecordNotFound or RecordInvalid.
+	Code        string
+	Description string
+	// Details are the validation errors of each field of a record Zendesk rejected.
+	Details map[string][]ErrorDetail
+	// RetryAfter is how long to wait before retrying a rate limited request.
+	RetryAfter time.Duration
+}
+
+// ErrorDetail is a validation error of a field.
+type ErrorDetail struct {
+	Error       string `json:"error"`
+	Description string `json:"description"`
+}
+
+// newAPIError parses the error Zendesk returned in the body of a response. Zendesk reports errors either as
+// {"error": "RecordNotFound", "description": "..."} or as {"error": {"title": "...", "message": "..."}}.
+func newAPIError(statusCode int, header http.Header, body []byte) *APIError {
+	e := &APIError{StatusCode: statusCode}
+
+	var result struct {
+		Error       json.RawMessage          `json:"error"`
+		Description string                   `json:"description"`
+		Details     map[string][]ErrorDetail `json:"details"`
+	}
+	if json.Unmarshal(body, &result) == nil {
+		var title struct {
+			Title   string `json:"title"`
+			Message string `json:"message"`
+		}
+		if json.Unmarshal(result.Error, &e.Code) != nil && json.Unmarshal(result.Error, &title) == nil {
+			e.Code = title.Title
+			e.Description = title.Message
+		}
+		if result.Description != "" {
+			e.Description = result.Description
+		}
+		e.Details = result.Details
+	}
+
+	if statusCode == http.StatusTooManyRequests {
+		e.RetryAfter = defaultRetryAfter
+		if seconds, err := strconv.Atoi(header.Get("Retry-After")); err == nil && seconds >= 0 {
+			e.RetryAfter = time.Duration(seconds) * time.Second
+		}
+	}
+
+	return e
+}
+
+func (e *APIError) Error() string {
+	msg := fmt.Sprintf("zendesk-connector: %d %s", e.StatusCode, http.StatusText(e.StatusCode))
+	if e.Code != "" {
+		msg += ": " + e.Code
+	}
+	if e.Description != "" {
+		msg += ": " + e.Description
+	}
+
+	fields := make([]string, 0, len(e.Details))
+	for field := range e.Details {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	var details []string
+	for _, field := range fields {
+		for _, detail := range e.Details[field] {
+			details = append(details, fmt.Sprintf("%s: %s", field, detail.Description))
+		}
+	}
+	if len(details) > 0 {
+		msg += " (" + strings.Join(details, "; ") + ")"
+	}
+
+	return msg
+}
+
+// IsNotFound reports whether err is a request Zendesk answered with 404 Not Found.
+func IsNotFound(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound
+}
+
+// GRPCCode returns the gRPC code matching the HTTP status of the error.
+func (e *APIError) GRPCCode() codes.Code {
+	switch {
+	case e.StatusCode == http.StatusUnauthorized:
+		return codes.Unauthenticated
+	case e.StatusCode == http.StatusForbidden:
+		return codes.PermissionDenied
+	case e.StatusCode == http.StatusNotFound:
+		return codes.NotFound
+	case e.StatusCode == http.StatusUnprocessableEntity:
+		return codes.InvalidArgument
+	case e.StatusCode == http.StatusTooManyRequests:
+		return codes.Unavailable
+	case e.StatusCode >= http.StatusInternalServerError:
+		return codes.Unavailable
+	default:
+		return codes.Unknown
+	}
+}
+
+// GRPCStatus returns the gRPC status of the error. Rate limited requests carry a rate limit description
+// telling the baton runner when to retry.
+func (e *APIError) GRPCStatus() *status.Status {
+	st := status.New(e.GRPCCode(), e.Error())
+	if e.StatusCode != http.StatusTooManyRequests {
+		return st
+	}
+
+	withDetails, err := st.WithDetails(&v2.RateLimitDescription{
+		Status:  v2.RateLimitDescription_STATUS_OVERLIMIT,
+		Limit:   1,
+		ResetAt: timestamppb.New(time.Now().Add(e.RetryAfter)),
+	})
+	if err != nil {
+		return st
+	}
+
+	return withDetails
+}
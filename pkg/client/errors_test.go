@@ -0,0 +1,77 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestAPIErrorStatus(t *testing.T) {
+	ctx := context.Background()
+
+	for _, tc := range []struct {
+		status   int
+		body     string
+		wantCode codes.Code
+		wantMsg  string
+	}{
+		{http.StatusUnauthorized, `{"error":"Couldn't authenticate you"}`, codes.Unauthenticated, "Couldn't authenticate you"},
+		{http.StatusForbidden, `{"error":{"title":"Forbidden","message":"You do not have access to this page"}}`, codes.PermissionDenied, "You do not have access"},
+		{http.StatusNotFound, `{"error":"RecordNotFound","description":"Not found"}`, codes.NotFound, "RecordNotFound: Not found"},
+		{
+			http.StatusUnprocessableEntity,
+			`{"error":"RecordInvalid","description":"Record validation errors","details":{"user_id":[{"error":"DuplicateValue","description":"User is already a member"}]}}`,
+			codes.InvalidArgument,
+			"user_id: User is already a member",
+		},
+		{http.StatusTooManyRequests, `{"error":"TooManyRequests"}`, codes.Unavailable, "TooManyRequests"},
+		{http.StatusServiceUnavailable, ``, codes.Unavailable, "503 Service Unavailable"},
+	} {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Retry-After", "30")
+			w.WriteHeader(tc.status)
+			fmt.Fprint(w, tc.body)
+		}))
+
+		z, err := New(ctx, nil, "example", "admin@example.com", "secret-token", WithEndpointURL(server.URL+"/api/v2"))
+		if err != nil {
+			t.Fatalf("creating client: %v", err)
+		}
+
+		_, err = z.GetUser(ctx, 1)
+		server.Close()
+
+		// The status survives the wrapping callers add.
+		err = fmt.Errorf("zendesk-connector: failed to get user: %w", err)
+		st, _ := status.FromError(err)
+		if st.Code() != tc.wantCode {
+			t.Errorf("status %d mapped to %s, want %s", tc.status, st.Code(), tc.wantCode)
+		}
+		if !strings.Contains(err.Error(), tc.wantMsg) {
+			t.Errorf("error %q does not contain %q", err, tc.wantMsg)
+		}
+
+		if tc.status == http.StatusTooManyRequests {
+			var description *v2.RateLimitDescription
+			for _, detail := range st.Details() {
+				if d, ok := detail.(*v2.RateLimitDescription); ok {
+					description = d
+				}
+			}
+			if description == nil {
+				t.Fatalf("rate limited error has no rate limit description")
+			}
+			if wait := time.Until(description.GetResetAt().AsTime()); wait < 25*time.Second || wait > 30*time.Second {
+				t.Errorf("rate limited error resets in %s, want 30s", wait)
+			}
+		}
+	}
+}
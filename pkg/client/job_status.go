@@ -0,0 +1,131 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	JobStatusQueued    = "queued"
+	JobStatusWorking   = "working"
+	JobStatusCompleted = "completed"
+	JobStatusFailed    = "failed"
+	JobStatusKilled    = "killed"
+)
+
+// JobStatus is the progress of a background job, such as a bulk create or delete.
+type JobStatus struct {
+	ID       string      `json:"id"`
+	URL      string      `json:"url,omitempty"`
+	Status   string      `json:"status"`
+	Total    int         `json:"total,omitempty"`
+	Progress int         `json:"progress,omitempty"`
+	Message  string      `json:"message,omitempty"`
+	Results  []JobResult `json:"results,omitempty"`
+}
+
+// JobResult is the outcome of one item of a background job. Items of bulk creates are identified by their index in
+// the request, and items of bulk deletes by their ID.
+type JobResult struct {
+	ID      int64  `json:"id,omitempty"`
+	Index   *int   `json:"index,omitempty"`
+	Success bool   `json:"success,omitempty"`
+	Status  string `json:"status,omitempty"`
+	Action  string `json:"action,omitempty"`
+	Error   string `json:"error,omitempty"`
+	Details string `json:"details,omitempty"`
+}
+
+// Failed reports whether the item could not be processed.
+func (r JobResult) Failed() bool {
+	return r.Error != "" || strings.EqualFold(r.Status, JobStatusFailed)
+}
+
+// Err returns the reason the item could not be processed, or nil if it succeeded.
+func (r JobResult) Err() error {
+	if !r.Failed() {
+		return nil
+	}
+	if r.Details != "" {
+		return fmt.Errorf("%s: %s", r.Error, r.Details)
+	}
+	if r.Error != "" {
+		return fmt.Errorf("%s", r.Error)
+	}
+
+	return fmt.Errorf("%s", r.Status)
+}
+
+// Done reports whether the job is no longer running.
+func (j JobStatus) Done() bool {
+	return j.Status != JobStatusQueued && j.Status != JobStatusWorking
+}
+
+// Err returns an error if the job as a whole did not complete.
+func (j JobStatus) Err() error {
+	if j.Status == JobStatusFailed || j.Status == JobStatusKilled {
+		return fmt.Errorf("zendesk-connector: job %s %s: %s", j.ID, j.Status, j.Message)
+	}
+
+	return nil
+}
+
+// GetJobStatus returns the current status of a background job.
+//
+// Zendesk API docs: https://developer.zendesk.com/api-reference/ticketing/ticket-management/job_statuses/#show-job-status
+func (z *ZendeskClient) GetJobStatus(ctx context.Context, jobID string) (JobStatus, error) {
+	var result struct {
+		JobStatus JobStatus `json:"job_status"`
+	}
+
+	body, err := z.client.Get(ctx, fmt.Sprintf("/job_statuses/%s.json", jobID))
+	if err != nil {
+		return JobStatus{}, err
+	}
+
+	err = json.Unmarshal(body, &result)
+	if err != nil {
+		return JobStatus{}, err
+	}
+
+	return result.JobStatus, nil
+}
+
+// WaitForJobStatus polls a background job every interval until it is done, and returns its final status.
+func (z *ZendeskClient) WaitForJobStatus(ctx context.Context, job JobStatus, interval time.Duration) (JobStatus, error) {
+	for !job.Done() {
+		select {
+		case <-ctx.Done():
+			return JobStatus{}, ctx.Err()
+		case <-time.After(interval):
+		}
+
+		next, err := z.GetJobStatus(ctx, job.ID)
+		if err != nil {
+			return JobStatus{}, fmt.Errorf("zendesk-connector: failed to get status of job %s: %w", job.ID, err)
+		}
+		job = next
+	}
+
+	return job, job.Err()
+}
+
+// jobStatusResponse decodes the job status returned when a background job is queued.
+func jobStatusResponse(body []byte) (JobStatus, error) {
+	var result struct {
+		JobStatus JobStatus `json:"job_status"`
+	}
+
+	err := json.Unmarshal(body, &result)
+	if err != nil {
+		return JobStatus{}, err
+	}
+	if result.JobStatus.ID == "" {
+		return JobStatus{}, fmt.Errorf("zendesk-connector: no job status in response")
+	}
+
+	return result.JobStatus, nil
+}
@@ -0,0 +1,272 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/nukosuke/go-zendesk/zendesk"
+)
+
+const membershipPageSize = 100
+
+// listMemberships returns a page of the memberships at path, along with their side-loaded users.
+// Memberships are paged with a cursor, since offset pagination stops after 100 pages on large accounts.
+func (z *ZendeskClient) listMemberships(ctx context.Context, path string, cursor string, memberships interface{}) ([]zendesk.User, string, error) {
+	var (
+		nextPageToken string
+		result        struct {
+			Users []zendesk.User               `json:"users"`
+			Meta  zendesk.CursorPaginationMeta `json:"meta"`
+		}
+	)
+
+	q := url.Values{}
+	q.Set("include", "users")
+	q.Set("page[size]", strconv.Itoa(membershipPageSize))
+	if cursor != "" {
+		q.Set("page[after]", cursor)
+	}
+
+	body, err := z.client.Get(ctx, path+"?"+q.Encode())
+	if err != nil {
+		return nil, "", err
+	}
+
+	err = json.Unmarshal(body, &result)
+	if err != nil {
+		return nil, "", err
+	}
+
+	err = json.Unmarshal(body, memberships)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if result.Meta.HasMore {
+		nextPageToken = result.Meta.AfterCursor
+	}
+
+	return result.Users, nextPageToken, nil
+}
+
+// ListAllGroupMemberships returns a page of the group memberships of the whole account, along with their users.
+//
+// Zendesk API docs: https://developer.zendesk.com/api-reference/ticketing/groups/group_memberships/#list-memberships
+func (z *ZendeskClient) ListAllGroupMemberships(ctx context.Context, cursor string) ([]zendesk.GroupMembership, []zendesk.User, string, error) {
+	var result struct {
+		GroupMemberships []zendesk.GroupMembership `json:"group_memberships"`
+	}
+
+	users, nextPageToken, err := z.listMemberships(ctx, "/group_memberships.json", cursor, &result)
+	if err != nil {
+		return nil, nil, "", err
+	}
+
+	return result.GroupMemberships, users, nextPageToken, nil
+}
+
+// ListAllOrganizationMemberships returns a page of the organization memberships of the whole account, along with their users.
+//
+// Zendesk API docs: https://developer.zendesk.com/api-reference/ticketing/organizations/organization_memberships/#list-memberships
+func (z *ZendeskClient) ListAllOrganizationMemberships(ctx context.Context, cursor string) ([]zendesk.OrganizationMembership, []zendesk.User, string, error) {
+	var result struct {
+		OrganizationMemberships []zendesk.OrganizationMembership `json:"organization_memberships"`
+	}
+
+	users, nextPageToken, err := z.listMemberships(ctx, "/organization_memberships.json", cursor, &result)
+	if err != nil {
+		return nil, nil, "", err
+	}
+
+	return result.OrganizationMemberships, users, nextPageToken, nil
+}
+
+// UserGroupMemberships returns every group membership of a user, reading all of its pages.
+//
+// Zendesk API docs: https://developer.zendesk.com/api-reference/ticketing/groups/group_memberships/#list-memberships
+func (z *ZendeskClient) UserGroupMemberships(ctx context.Context, userID int64) ([]zendesk.GroupMembership, error) {
+	var (
+		memberships []zendesk.GroupMembership
+		cursor      string
+	)
+	for {
+		var result struct {
+			GroupMemberships []zendesk.GroupMembership `json:"group_memberships"`
+		}
+
+		_, nextPageToken, err := z.listMemberships(ctx, fmt.Sprintf("/users/%d/group_memberships.json", userID), cursor, &result)
+		if err != nil {
+			return nil, err
+		}
+
+		memberships = append(memberships, result.GroupMemberships...)
+		if nextPageToken == "" {
+			return memberships, nil
+		}
+		cursor = nextPageToken
+	}
+}
+
+// UserOrganizationMemberships returns every organization membership of a user, reading all of its pages.
+//
+// Zendesk API docs: https://developer.zendesk.com/api-reference/ticketing/organizations/organization_memberships/#list-memberships
+func (z *ZendeskClient) UserOrganizationMemberships(ctx context.Context, userID int64) ([]zendesk.OrganizationMembership, error) {
+	var (
+		memberships []zendesk.OrganizationMembership
+		cursor      string
+	)
+	for {
+		var result struct {
+			OrganizationMemberships []zendesk.OrganizationMembership `json:"organization_memberships"`
+		}
+
+		_, nextPageToken, err := z.listMemberships(ctx, fmt.Sprintf("/users/%d/organization_memberships.json", userID), cursor, &result)
+		if err != nil {
+			return nil, err
+		}
+
+		memberships = append(memberships, result.OrganizationMemberships...)
+		if nextPageToken == "" {
+			return memberships, nil
+		}
+		cursor = nextPageToken
+	}
+}
+
+// DeleteGroupMembership removes a group membership by its ID.
+//
+// Zendesk API docs: https://developer.zendesk.com/api-reference/ticketing/groups/group_memberships/#delete-membership
+func (z *ZendeskClient) DeleteGroupMembership(ctx context.Context, membershipID int64) error {
+	return z.client.Delete(ctx, fmt.Sprintf("/group_memberships/%d", membershipID))
+}
+
+// DeleteOrganizationMembership removes an organization membership by its ID.
+//
+// Zendesk API docs: https://developer.zendesk.com/api-reference/ticketing/organizations/organization_memberships/#delete-membership
+func (z *ZendeskClient) DeleteOrganizationMembership(ctx context.Context, membershipID int64) error {
+	return z.client.Delete(ctx, fmt.Sprintf("/organization_memberships/%d", membershipID))
+}
+
+// CreateManyGroupMemberships queues a job adding agents to groups, up to 100 at once.
+//
+// Zendesk API docs: https://developer.zendesk.com/api-reference/ticketing/groups/group_memberships/#bulk-create-memberships
+func (z *ZendeskClient) CreateManyGroupMemberships(ctx context.Context, memberships []zendesk.GroupMembership) (JobStatus, error) {
+	var data struct {
+		GroupMemberships []zendesk.GroupMembership `json:"group_memberships"`
+	}
+
+	data.GroupMemberships = memberships
+	body, err := z.client.Post(ctx, "/group_memberships/create_many.json", data)
+	if err != nil {
+		return JobStatus{}, err
+	}
+
+	return jobStatusResponse(body)
+}
+
+// DestroyManyGroupMemberships queues a job removing group memberships, up to 100 at once.
+//
+// Zendesk API docs: https://developer.zendesk.com/api-reference/ticketing/groups/group_memberships/#bulk-delete-memberships
+func (z *ZendeskClient) DestroyManyGroupMemberships(ctx context.Context, membershipIDs []int64) (JobStatus, error) {
+	body, err := z.deleteMany(ctx, "/group_memberships/destroy_many.json", membershipIDs)
+	if err != nil {
+		return JobStatus{}, err
+	}
+
+	return jobStatusResponse(body)
+}
+
+// CreateManyOrganizationMemberships queues a job adding users to organizations, up to 100 at once.
+//
+// Zendesk API docs: https://developer.zendesk.com/api-reference/ticketing/organizations/organization_memberships/#create-many-memberships
+func (z *ZendeskClient) CreateManyOrganizationMemberships(ctx context.Context, memberships []zendesk.OrganizationMembership) (JobStatus, error) {
+	var data struct {
+		OrganizationMemberships []zendesk.OrganizationMembership `json:"organization_memberships"`
+	}
+
+	data.OrganizationMemberships = memberships
+	body, err := z.client.Post(ctx, "/organization_memberships/create_many.json", data)
+	if err != nil {
+		return JobStatus{}, err
+	}
+
+	return jobStatusResponse(body)
+}
+
+// DestroyManyOrganizationMemberships queues a job removing organization memberships, up to 100 at once.
+//
+// Zendesk API docs: https://developer.zendesk.com/api-reference/ticketing/organizations/organization_memberships/#bulk-delete-memberships
+func (z *ZendeskClient) DestroyManyOrganizationMemberships(ctx context.Context, membershipIDs []int64) (JobStatus, error) {
+	body, err := z.deleteMany(ctx, "/organization_memberships/destroy_many.json", membershipIDs)
+	if err != nil {
+		return JobStatus{}, err
+	}
+
+	return jobStatusResponse(body)
+}
+
+// GroupMembershipIDs returns the IDs of the memberships of users in a group, keyed by user ID.
+// Memberships are looked up per user, since a group can hold far more agents than an agent has groups.
+func (z *ZendeskClient) GroupMembershipIDs(ctx context.Context, groupID int64, userIDs []int64) (map[int64]int64, error) {
+	ids := make(map[int64]int64, len(userIDs))
+	for _, userID := range userIDs {
+		memberships, err := z.UserGroupMemberships(ctx, userID)
+		if err != nil {
+			return nil, fmt.Errorf("zendesk-connector: failed to list group memberships of user %d: %w", userID, err)
+		}
+
+		for _, membership := range memberships {
+			if membership.GroupID == groupID {
+				ids[userID] = membership.ID
+			}
+		}
+	}
+
+	return ids, nil
+}
+
+// OrganizationMembershipIDs returns the IDs of the memberships of users in an organization, keyed by user ID.
+// Memberships are looked up per user, since organizations can hold far more end-users than a user has organizations.
+func (z *ZendeskClient) OrganizationMembershipIDs(ctx context.Context, orgID int64, userIDs []int64) (map[int64]int64, error) {
+	ids := make(map[int64]int64, len(userIDs))
+	for _, userID := range userIDs {
+		memberships, err := z.UserOrganizationMemberships(ctx, userID)
+		if err != nil {
+			return nil, fmt.Errorf("zendesk-connector: failed to list organization memberships of user %d: %w", userID, err)
+		}
+
+		for _, membership := range memberships {
+			if membership.OrganizationID == orgID {
+				ids[userID] = membership.ID
+			}
+		}
+	}
+
+	return ids, nil
+}
+
+// deleteMany sends a bulk delete of the given IDs and returns the response body. Bulk deletes answer with a job
+// status and 200 rather than 204, which go-zendesk's Delete reports as an error carrying the body.
+func (z *ZendeskClient) deleteMany(ctx context.Context, path string, ids []int64) ([]byte, error) {
+	values := make([]string, 0, len(ids))
+	for _, id := range ids {
+		values = append(values, strconv.FormatInt(id, 10))
+	}
+
+	q := url.Values{}
+	q.Set("ids", strings.Join(values, ","))
+
+	body, err := z.client.DeleteWithResponse(ctx, path+"?"+q.Encode())
+	if err != nil {
+		return nil, err
+	}
+	if body == nil {
+		return nil, fmt.Errorf("zendesk-connector: no job status in response")
+	}
+
+	return body, nil
+}
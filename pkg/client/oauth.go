@@ -0,0 +1,108 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/nukosuke/go-zendesk/zendesk"
+)
+
+// OAuthClient is an OAuth client registered in Zendesk.
+type OAuthClient struct {
+	ID          int64     `json:"id,omitempty"`
+	URL         string    `json:"url,omitempty"`
+	Name        string    `json:"name,omitempty"`
+	Identifier  string    `json:"identifier,omitempty"`
+	Company     string    `json:"company,omitempty"`
+	Description string    `json:"description,omitempty"`
+	Kind        string    `json:"kind,omitempty"`
+	UserID      int64     `json:"user_id,omitempty"`
+	RedirectURI []string  `json:"redirect_uri,omitempty"`
+	CreatedAt   time.Time `json:"created_at,omitempty"`
+	UpdatedAt   time.Time `json:"updated_at,omitempty"`
+}
+
+// OAuthToken is an OAuth access token issued to a client on behalf of a user.
+type OAuthToken struct {
+	ID        int64     `json:"id,omitempty"`
+	URL       string    `json:"url,omitempty"`
+	ClientID  int64     `json:"client_id,omitempty"`
+	UserID    int64     `json:"user_id,omitempty"`
+	Scopes    []string  `json:"scopes,omitempty"`
+	CreatedAt time.Time `json:"created_at,omitempty"`
+	ExpiresAt time.Time `json:"expires_at,omitempty"`
+	UsedAt    time.Time `json:"used_at,omitempty"`
+}
+
+// ListOAuthClients returns the OAuth clients of the account.
+//
+// Zendesk API docs: https://developer.zendesk.com/api-reference/ticketing/oauth/oauth_clients/#list-clients
+func (z *ZendeskClient) ListOAuthClients(ctx context.Context, pageToken int) ([]OAuthClient, string, error) {
+	var (
+		nextPageToken string
+		result        struct {
+			Clients []OAuthClient `json:"clients"`
+			zendesk.Page
+		}
+	)
+
+	body, err := z.client.Get(ctx, withPage("/oauth/clients.json", pageToken))
+	if err != nil {
+		return nil, "", err
+	}
+
+	err = json.Unmarshal(body, &result)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if result.NextPage != nil {
+		nextPageToken, err = parseNextPage(*result.NextPage)
+		if err != nil {
+			return nil, "", err
+		}
+	}
+
+	return result.Clients, nextPageToken, nil
+}
+
+// ListOAuthTokens returns the OAuth tokens issued for every user of the account.
+//
+// Zendesk API docs: https://developer.zendesk.com/api-reference/ticketing/oauth/oauth_tokens/#list-tokens
+func (z *ZendeskClient) ListOAuthTokens(ctx context.Context, pageToken int) ([]OAuthToken, string, error) {
+	var (
+		nextPageToken string
+		result        struct {
+			Tokens []OAuthToken `json:"tokens"`
+			zendesk.Page
+		}
+	)
+
+	body, err := z.client.Get(ctx, withPage("/oauth/tokens.json?all=true", pageToken))
+	if err != nil {
+		return nil, "", err
+	}
+
+	err = json.Unmarshal(body, &result)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if result.NextPage != nil {
+		nextPageToken, err = parseNextPage(*result.NextPage)
+		if err != nil {
+			return nil, "", err
+		}
+	}
+
+	return result.Tokens, nextPageToken, nil
+}
+
+// RevokeOAuthToken revokes an OAuth token.
+//
+// Zendesk API docs: https://developer.zendesk.com/api-reference/ticketing/oauth/oauth_tokens/#revoke-token
+func (z *ZendeskClient) RevokeOAuthToken(ctx context.Context, tokenID int64) error {
+	return z.client.Delete(ctx, fmt.Sprintf("/oauth/tokens/%d.json", tokenID))
+}
@@ -0,0 +1,66 @@
+package client
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+)
+
+// RateLimitTransport keeps track of the rate limit quota Zendesk reports on every response.
+type RateLimitTransport struct {
+	next http.RoundTripper
+
+	mu        sync.Mutex
+	limit     int
+	remaining int
+	seen      bool
+}
+
+// NewRateLimitTransport returns a transport recording the quota reported by the responses it forwards.
+func NewRateLimitTransport(next http.RoundTripper) *RateLimitTransport {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+
+	return &RateLimitTransport{
+		next: next,
+	}
+}
+
+func (t *RateLimitTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	limit, limitErr := strconv.Atoi(firstHeader(resp.Header, "X-Rate-Limit", "Ratelimit-Limit"))
+	remaining, remainingErr := strconv.Atoi(firstHeader(resp.Header, "X-Rate-Limit-Remaining", "Ratelimit-Remaining"))
+	if limitErr == nil && remainingErr == nil && limit > 0 {
+		t.mu.Lock()
+		t.limit = limit
+		t.remaining = remaining
+		t.seen = true
+		t.mu.Unlock()
+	}
+
+	return resp, nil
+}
+
+// Quota returns the requests remaining in the current rate limit window and the size of the window,
+// as reported by the last response carrying rate limit headers.
+func (t *RateLimitTransport) Quota() (int, int, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.remaining, t.limit, t.seen
+}
+
+func firstHeader(header http.Header, names ...string) string {
+	for _, name := range names {
+		if value := header.Get(name); value != "" {
+			return value
+		}
+	}
+
+	return ""
+}
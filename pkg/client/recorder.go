@@ -0,0 +1,398 @@
+package client
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
+)
+
+const redacted = "REDACTED"
+
+// secretKeys are JSON keys whose values are always redacted from recordings.
+var secretKeys = map[string]struct{}{
+	"token":          {},
+	"full_token":     {},
+	"access_token":   {},
+	"refresh_token":  {},
+	"api_token":      {},
+	"secret":         {},
+	"client_secret":  {},
+	"signing_secret": {},
+	"password":       {},
+}
+
+// piiKeys are JSON keys whose values are redacted from recordings when PII redaction is enabled, along with every
+// value nested under them. Values are replaced by a stable hash so that the same person keeps the same placeholder
+// across exchanges.
+var piiKeys = map[string]struct{}{
+	"name":        {},
+	"email":       {},
+	"phone":       {},
+	"alias":       {},
+	"value":       {},
+	"details":     {},
+	"notes":       {},
+	"signature":   {},
+	"external_id": {},
+	"tags":        {},
+	"user_fields": {},
+}
+
+// safeQueryKeys are query parameters kept in recorded paths when PII redaction is enabled. They only carry
+// pagination, IDs and types, while the values of every other parameter, such as search queries, are redacted.
+var safeQueryKeys = map[string]struct{}{
+	"page":         {},
+	"per_page":     {},
+	"page[size]":   {},
+	"page[after]":  {},
+	"page[before]": {},
+	"sort_by":      {},
+	"sort_order":   {},
+	"include":      {},
+	"ids":          {},
+	"role":         {},
+	"role[]":       {},
+	"filter[type]": {},
+}
+
+// recordedHeaders are the response headers kept in recordings.
+var recordedHeaders = []string{
+	"Content-Type",
+	"Retry-After",
+	"X-Rate-Limit",
+	"X-Rate-Limit-Remaining",
+	"Ratelimit-Remaining",
+}
+
+// Exchange is a recorded request and response pair.
+type Exchange struct {
+	Method       string              `json:"method"`
+	Path         string              `json:"path"`
+	RequestBody  json.RawMessage     `json:"request_body,omitempty"`
+	Status       int                 `json:"status"`
+	Header       map[string][]string `json:"header,omitempty"`
+	ResponseBody json.RawMessage     `json:"response_body,omitempty"`
+}
+
+func (e *Exchange) key() string {
+	return e.Method + " " + e.Path
+}
+
+// requestPath returns the path and query of a request relative to the API endpoint,
+// so that recordings replay against any subdomain.
+func requestPath(r *http.Request) string {
+	path := r.URL.Path
+	if i := strings.Index(path, "/api/v2"); i >= 0 {
+		path = path[i+len("/api/v2"):]
+	}
+	if r.URL.RawQuery != "" {
+		path += "?" + r.URL.RawQuery
+	}
+
+	return path
+}
+
+// redactPath redacts the path segments that look like emails and the values of query parameters that are not
+// known to be safe. Paths without personal data are returned unchanged.
+func redactPath(path string) string {
+	path, rawQuery, hasQuery := strings.Cut(path, "?")
+
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		if unescaped, err := url.PathUnescape(segment); err == nil && strings.Contains(unescaped, "@") {
+			segments[i] = pseudonym(unescaped)
+		}
+	}
+	path = strings.Join(segments, "/")
+
+	if !hasQuery {
+		return path
+	}
+
+	query, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		return path + "?" + redacted
+	}
+
+	changed := false
+	for key, values := range query {
+		if _, ok := safeQueryKeys[key]; ok {
+			continue
+		}
+		for i, value := range values {
+			values[i] = pseudonym(value)
+			changed = true
+		}
+	}
+	if !changed {
+		return path + "?" + rawQuery
+	}
+
+	return path + "?" + query.Encode()
+}
+
+type recordingTransport struct {
+	next      http.RoundTripper
+	dir       string
+	redactPII bool
+
+	mu  sync.Mutex
+	seq int
+}
+
+// NewRecordingTransport returns a transport that saves every request and response pair in dir, one file per exchange.
+// Credentials and secrets are never recorded. Names, emails and other personal data are redacted from bodies, paths and
+// queries when redactPII is set. Recording failures are logged without failing the request.
+func NewRecordingTransport(next http.RoundTripper, dir string, redactPII bool) (http.RoundTripper, error) {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+
+	err := os.MkdirAll(dir, 0o755)
+	if err != nil {
+		return nil, fmt.Errorf("zendesk-connector: failed to create recording directory: %w", err)
+	}
+
+	return &recordingTransport{
+		next:      next,
+		dir:       dir,
+		redactPII: redactPII,
+	}, nil
+}
+
+func (t *recordingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var requestBody []byte
+	if req.Body != nil {
+		body, err := io.ReadAll(req.Body)
+		if err != nil {
+			return nil, err
+		}
+		req.Body.Close()
+		requestBody = body
+		req.Body = io.NopCloser(bytes.NewReader(body))
+	}
+
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	responseBody, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(responseBody))
+
+	path := requestPath(req)
+	if t.redactPII {
+		path = redactPath(path)
+	}
+
+	exchange := &Exchange{
+		Method:       req.Method,
+		Path:         path,
+		RequestBody:  t.sanitize(requestBody),
+		Status:       resp.StatusCode,
+		Header:       make(map[string][]string),
+		ResponseBody: t.sanitize(responseBody),
+	}
+	for _, name := range recordedHeaders {
+		if values := resp.Header.Values(name); len(values) > 0 {
+			exchange.Header[name] = values
+		}
+	}
+
+	err = t.save(exchange)
+	if err != nil {
+		ctxzap.Extract(req.Context()).Error("zendesk-connector: failed to record exchange",
+			zap.String("method", exchange.Method),
+			zap.String("path", exchange.Path),
+			zap.Error(err),
+		)
+	}
+
+	return resp, nil
+}
+
+func (t *recordingTransport) save(exchange *Exchange) error {
+	data, err := json.MarshalIndent(exchange, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.seq++
+	name := filepath.Join(t.dir, fmt.Sprintf("%06d.json", t.seq))
+
+	err = os.WriteFile(name, data, 0o600)
+	if err != nil {
+		return fmt.Errorf("zendesk-connector: failed to record exchange: %w", err)
+	}
+
+	return nil
+}
+
+// sanitize redacts secrets, and personal data if enabled, from a JSON body. Bodies that are not JSON are dropped.
+func (t *recordingTransport) sanitize(body []byte) json.RawMessage {
+	if len(bytes.TrimSpace(body)) == 0 {
+		return nil
+	}
+
+	// Numbers are kept as written, so that large IDs do not lose precision.
+	var value interface{}
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	err := decoder.Decode(&value)
+	if err != nil {
+		return nil
+	}
+
+	data, err := json.Marshal(t.redact("", value, false))
+	if err != nil {
+		return nil
+	}
+
+	return data
+}
+
+// redact redacts a JSON value found under key. Values nested under a PII key are personal data whatever their key.
+func (t *recordingTransport) redact(key string, value interface{}, pii bool) interface{} {
+	if _, ok := piiKeys[key]; ok && t.redactPII {
+		pii = true
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for k, item := range v {
+			v[k] = t.redact(k, item, pii)
+		}
+		return v
+	case []interface{}:
+		for i, item := range v {
+			v[i] = t.redact(key, item, pii)
+		}
+		return v
+	case string:
+		if _, ok := secretKeys[key]; ok {
+			return redacted
+		}
+		if pii {
+			return pseudonym(v)
+		}
+		return v
+	default:
+		return v
+	}
+}
+
+// pseudonym replaces a value by a stable placeholder, keeping emails shaped like emails.
+func pseudonym(value string) string {
+	if value == "" {
+		return value
+	}
+
+	sum := sha256.Sum256([]byte(value))
+	placeholder := "redacted-" + hex.EncodeToString(sum[:6])
+	if strings.Contains(value, "@") {
+		return placeholder + "@example.invalid"
+	}
+
+	return placeholder
+}
+
+type replayTransport struct {
+	mu        sync.Mutex
+	exchanges map[string][]*Exchange
+}
+
+// NewReplayTransport returns a transport that answers requests with the exchanges recorded in dir, without any
+// network access. Repeated requests are answered in recording order, and the last response is repeated after that.
+func NewReplayTransport(dir string) (http.RoundTripper, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("zendesk-connector: no recorded exchanges found in %s", dir)
+	}
+	sort.Strings(files)
+
+	t := &replayTransport{
+		exchanges: make(map[string][]*Exchange),
+	}
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+
+		exchange := &Exchange{}
+		err = json.Unmarshal(data, exchange)
+		if err != nil {
+			return nil, fmt.Errorf("zendesk-connector: invalid recorded exchange %s: %w", file, err)
+		}
+
+		t.exchanges[exchange.key()] = append(t.exchanges[exchange.key()], exchange)
+	}
+
+	return t, nil
+}
+
+func (t *replayTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body != nil {
+		req.Body.Close()
+	}
+
+	// Recordings made with PII redaction hold redacted paths, which the same redaction of the request matches.
+	key := req.Method + " " + requestPath(req)
+
+	t.mu.Lock()
+	if _, ok := t.exchanges[key]; !ok {
+		key = req.Method + " " + redactPath(requestPath(req))
+	}
+	exchanges := t.exchanges[key]
+	if len(exchanges) == 0 {
+		t.mu.Unlock()
+		return nil, fmt.Errorf("zendesk-connector: no recorded response for %s", key)
+	}
+	exchange := exchanges[0]
+	if len(exchanges) > 1 {
+		t.exchanges[key] = exchanges[1:]
+	}
+	t.mu.Unlock()
+
+	header := make(http.Header)
+	for name, values := range exchange.Header {
+		for _, value := range values {
+			header.Add(name, value)
+		}
+	}
+
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", exchange.Status, http.StatusText(exchange.Status)),
+		StatusCode:    exchange.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(exchange.ResponseBody)),
+		ContentLength: int64(len(exchange.ResponseBody)),
+		Request:       req,
+	}, nil
+}
@@ -0,0 +1,158 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/conductorone/baton-zendesk/pkg/zendesktest"
+)
+
+func TestRecordAndReplay(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	server := zendesktest.NewServer(zendesktest.DefaultFixtures())
+	defer server.Close()
+
+	recorder, err := NewRecordingTransport(http.DefaultTransport, dir, true)
+	if err != nil {
+		t.Fatalf("creating recorder: %v", err)
+	}
+	recording, err := New(ctx, &http.Client{Transport: recorder}, "example", "admin@example.com", "secret-token", WithEndpointURL(server.EndpointURL()))
+	if err != nil {
+		t.Fatalf("creating client: %v", err)
+	}
+
+	recorded, err := recording.GetUser(ctx, 1)
+	if err != nil {
+		t.Fatalf("getting user: %v", err)
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil || len(files) != 1 {
+		t.Fatalf("got %d recorded exchanges, want 1 (%v)", len(files), err)
+	}
+	data, err := os.ReadFile(files[0])
+	if err != nil {
+		t.Fatalf("reading recording: %v", err)
+	}
+	for _, leaked := range []string{"secret-token", "alice@example.com", "Alice Admin"} {
+		if strings.Contains(string(data), leaked) {
+			t.Errorf("recording contains %q", leaked)
+		}
+	}
+
+	// Replaying needs neither the server nor credentials.
+	server.Close()
+	replay, err := NewReplayTransport(dir)
+	if err != nil {
+		t.Fatalf("creating replay: %v", err)
+	}
+	replaying, err := New(ctx, &http.Client{Transport: replay}, "example", "", "")
+	if err != nil {
+		t.Fatalf("creating client: %v", err)
+	}
+
+	replayed, err := replaying.GetUser(ctx, 1)
+	if err != nil {
+		t.Fatalf("replaying user: %v", err)
+	}
+	if replayed.ID != recorded.ID || replayed.Role != recorded.Role {
+		t.Errorf("replayed user %d (%s), want %d (%s)", replayed.ID, replayed.Role, recorded.ID, recorded.Role)
+	}
+	if !strings.HasSuffix(replayed.Email, "@example.invalid") {
+		t.Errorf("replayed email %q is not redacted", replayed.Email)
+	}
+
+	_, err = replaying.GetUser(ctx, 2)
+	if err == nil {
+		t.Errorf("replaying an unrecorded request succeeded")
+	}
+}
+
+func TestRecordRedactsPathsAndQueries(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	server := zendesktest.NewServer(zendesktest.DefaultFixtures())
+	defer server.Close()
+
+	recorder, err := NewRecordingTransport(http.DefaultTransport, dir, true)
+	if err != nil {
+		t.Fatalf("creating recorder: %v", err)
+	}
+	recording, err := New(ctx, &http.Client{Transport: recorder}, "example", "admin@example.com", "secret-token", WithEndpointURL(server.EndpointURL()))
+	if err != nil {
+		t.Fatalf("creating client: %v", err)
+	}
+
+	users, _, err := recording.SearchUsers(ctx, "external_id:idp-alice", "")
+	if err != nil || len(users) != 1 {
+		t.Fatalf("got %d users searching by external ID, want 1 (%v)", len(users), err)
+	}
+	err = recording.AddUserTags(ctx, 3, []string{"access-secret-project"})
+	if err != nil {
+		t.Fatalf("tagging user: %v", err)
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil || len(files) != 2 {
+		t.Fatalf("got %d recorded exchanges, want 2 (%v)", len(files), err)
+	}
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatalf("reading recording: %v", err)
+		}
+		for _, leaked := range []string{"idp-alice", "alice@example.com", "access-secret-project", "access-billing"} {
+			if strings.Contains(string(data), leaked) {
+				t.Errorf("recording %s contains %q", filepath.Base(file), leaked)
+			}
+		}
+	}
+
+	// Requests are matched against the redacted paths when replaying.
+	replay, err := NewReplayTransport(dir)
+	if err != nil {
+		t.Fatalf("creating replay: %v", err)
+	}
+	replaying, err := New(ctx, &http.Client{Transport: replay}, "example", "", "")
+	if err != nil {
+		t.Fatalf("creating client: %v", err)
+	}
+	users, _, err = replaying.SearchUsers(ctx, "external_id:idp-alice", "")
+	if err != nil || len(users) != 1 || users[0].ID != 1 {
+		t.Errorf("replayed search returned %v (%v), want user 1", users, err)
+	}
+}
+
+func TestRecordFailureDoesNotFailRequests(t *testing.T) {
+	ctx := context.Background()
+	dir := filepath.Join(t.TempDir(), "recordings")
+
+	server := zendesktest.NewServer(zendesktest.DefaultFixtures())
+	defer server.Close()
+
+	recorder, err := NewRecordingTransport(http.DefaultTransport, dir, false)
+	if err != nil {
+		t.Fatalf("creating recorder: %v", err)
+	}
+	recording, err := New(ctx, &http.Client{Transport: recorder}, "example", "admin@example.com", "secret-token", WithEndpointURL(server.EndpointURL()))
+	if err != nil {
+		t.Fatalf("creating client: %v", err)
+	}
+
+	err = os.RemoveAll(dir)
+	if err != nil {
+		t.Fatalf("removing recording directory: %v", err)
+	}
+
+	_, err = recording.GetUser(ctx, 1)
+	if err != nil {
+		t.Errorf("getting user with a failing recorder: %v", err)
+	}
+}
@@ -0,0 +1,41 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+)
+
+type requestBodyKey struct{}
+
+// withRequestBody attaches a JSON body to the request made with ctx. go-zendesk sends DELETE requests without
+// a body, but some endpoints, like removing tags, take their arguments in it.
+func withRequestBody(ctx context.Context, body []byte) context.Context {
+	return context.WithValue(ctx, requestBodyKey{}, body)
+}
+
+// requestBodyTransport sets the body attached to the context of a request that has none.
+type requestBodyTransport struct {
+	next http.RoundTripper
+}
+
+func newRequestBodyTransport(next http.RoundTripper) *requestBodyTransport {
+	return &requestBodyTransport{next: next}
+}
+
+func (t *requestBodyTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	body, ok := req.Context().Value(requestBodyKey{}).([]byte)
+	if !ok || req.Body != nil {
+		return t.next.RoundTrip(req)
+	}
+
+	req = req.Clone(req.Context())
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	req.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(body)), nil
+	}
+	req.ContentLength = int64(len(body))
+
+	return t.next.RoundTrip(req)
+}
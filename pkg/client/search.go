@@ -0,0 +1,82 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"net/url"
+	"strconv"
+
+	"github.com/nukosuke/go-zendesk/zendesk"
+)
+
+const searchExportPageSize = 1000
+
+const (
+	SearchTypeUser         = "user"
+	SearchTypeOrganization = "organization"
+)
+
+// searchExport returns a page of search results of a single type. The search export API is not limited to
+// 1000 results like the search API, and only supports cursor pagination.
+//
+// Zendesk API docs: https://developer.zendesk.com/api-reference/ticketing/ticket-management/search/#export-search-results
+func (z *ZendeskClient) searchExport(ctx context.Context, query string, resultType string, cursor string, results interface{}) (string, error) {
+	var (
+		nextPageToken string
+		result        struct {
+			Results json.RawMessage              `json:"results"`
+			Meta    zendesk.CursorPaginationMeta `json:"meta"`
+		}
+	)
+
+	q := url.Values{}
+	q.Set("query", query)
+	q.Set("filter[type]", resultType)
+	q.Set("page[size]", strconv.Itoa(searchExportPageSize))
+	if cursor != "" {
+		q.Set("page[after]", cursor)
+	}
+
+	body, err := z.client.Get(ctx, "/search/export.json?"+q.Encode())
+	if err != nil {
+		return "", err
+	}
+
+	err = json.Unmarshal(body, &result)
+	if err != nil {
+		return "", err
+	}
+
+	err = json.Unmarshal(result.Results, results)
+	if err != nil {
+		return "", err
+	}
+
+	if result.Meta.HasMore {
+		nextPageToken = result.Meta.AfterCursor
+	}
+
+	return nextPageToken, nil
+}
+
+// SearchUsers returns a page of users matching a search query.
+func (z *ZendeskClient) SearchUsers(ctx context.Context, query string, cursor string) ([]zendesk.User, string, error) {
+	var users []zendesk.User
+	nextPageToken, err := z.searchExport(ctx, query, SearchTypeUser, cursor, &users)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return users, nextPageToken, nil
+}
+
+// SearchOrganizations returns a page of organizations matching a search query.
+func (z *ZendeskClient) SearchOrganizations(ctx context.Context, query string, cursor string) ([]zendesk.Organization, string, error) {
+	var orgs []zendesk.Organization
+	nextPageToken, err := z.searchExport(ctx, query, SearchTypeOrganization, cursor, &orgs)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return orgs, nextPageToken, nil
+}
@@ -0,0 +1,68 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/nukosuke/go-zendesk/zendesk"
+)
+
+// Session is an active browser session of a user.
+type Session struct {
+	ID              int64     `json:"id,omitempty"`
+	UserID          int64     `json:"user_id,omitempty"`
+	URL             string    `json:"url,omitempty"`
+	AuthenticatedAt time.Time `json:"authenticated_at,omitempty"`
+	LastSeenAt      time.Time `json:"last_seen_at,omitempty"`
+}
+
+// ListUserSessions returns all the active sessions of a user. The returned slice is never nil.
+//
+// Zendesk API docs: https://developer.zendesk.com/api-reference/ticketing/account-configuration/sessions/#list-sessions
+func (z *ZendeskClient) ListUserSessions(ctx context.Context, userID int64) ([]Session, error) {
+	var (
+		sessions  = []Session{}
+		pageToken int
+	)
+
+	for {
+		var result struct {
+			Sessions []Session `json:"sessions"`
+			zendesk.Page
+		}
+
+		body, err := z.client.Get(ctx, withPage(fmt.Sprintf("/users/%d/sessions.json", userID), pageToken))
+		if err != nil {
+			return nil, err
+		}
+
+		err = json.Unmarshal(body, &result)
+		if err != nil {
+			return nil, err
+		}
+
+		sessions = append(sessions, result.Sessions...)
+		if result.NextPage == nil {
+			return sessions, nil
+		}
+
+		nextPageToken, err := parseNextPage(*result.NextPage)
+		if err != nil {
+			return nil, err
+		}
+		pageToken, err = strconv.Atoi(nextPageToken)
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// DeleteUserSessions signs a user out of every active session.
+//
+// Zendesk API docs: https://developer.zendesk.com/api-reference/ticketing/account-configuration/sessions/#bulk-delete-sessions
+func (z *ZendeskClient) DeleteUserSessions(ctx context.Context, userID int64) error {
+	return z.client.Delete(ctx, fmt.Sprintf("/users/%d/sessions.json", userID))
+}
@@ -0,0 +1,61 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// Tag is a tag of users or organizations.
+type Tag struct {
+	Name string `json:"name"`
+}
+
+// AddUserTags adds tags to a user, keeping the tags it already has.
+//
+// Zendesk API docs: https://developer.zendesk.com/api-reference/ticketing/ticket-management/tags/#add-tags
+func (z *ZendeskClient) AddUserTags(ctx context.Context, userID int64, tags []string) error {
+	return z.addTags(ctx, fmt.Sprintf("/users/%d/tags.json", userID), tags)
+}
+
+// RemoveUserTags removes tags from a user.
+//
+// Zendesk API docs: https://developer.zendesk.com/api-reference/ticketing/ticket-management/tags/#remove-tags
+func (z *ZendeskClient) RemoveUserTags(ctx context.Context, userID int64, tags []string) error {
+	return z.removeTags(ctx, fmt.Sprintf("/users/%d/tags.json", userID), tags)
+}
+
+// AddOrganizationTags adds tags to an organization, keeping the tags it already has.
+func (z *ZendeskClient) AddOrganizationTags(ctx context.Context, organizationID int64, tags []string) error {
+	return z.addTags(ctx, fmt.Sprintf("/organizations/%d/tags.json", organizationID), tags)
+}
+
+// RemoveOrganizationTags removes tags from an organization.
+func (z *ZendeskClient) RemoveOrganizationTags(ctx context.Context, organizationID int64, tags []string) error {
+	return z.removeTags(ctx, fmt.Sprintf("/organizations/%d/tags.json", organizationID), tags)
+}
+
+func (z *ZendeskClient) addTags(ctx context.Context, path string, tags []string) error {
+	data := struct {
+		Tags []string `json:"tags"`
+	}{
+		Tags: tags,
+	}
+
+	_, err := z.client.Put(ctx, path, data)
+	return err
+}
+
+// removeTags sends the tags in the body of the DELETE request, like the API documents it.
+func (z *ZendeskClient) removeTags(ctx context.Context, path string, tags []string) error {
+	data, err := json.Marshal(struct {
+		Tags []string `json:"tags"`
+	}{
+		Tags: tags,
+	})
+	if err != nil {
+		return err
+	}
+
+	return z.client.Delete(withRequestBody(ctx, data), path)
+}
@@ -0,0 +1,62 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/nukosuke/go-zendesk/zendesk"
+)
+
+// UserRoleUpdate changes the role of a user. A nil CustomRoleID is sent as null, which clears the custom role.
+type UserRoleUpdate struct {
+	Role         string `json:"role,omitempty"`
+	CustomRoleID *int64 `json:"custom_role_id"`
+}
+
+// UpdateUserRole updates the role and custom role of a user in a single request.
+//
+// Zendesk API docs: https://developer.zendesk.com/api-reference/ticketing/users/users/#update-user
+func (z *ZendeskClient) UpdateUserRole(ctx context.Context, userID int64, update UserRoleUpdate) (zendesk.User, error) {
+	var result struct {
+		User zendesk.User `json:"user"`
+	}
+
+	data := struct {
+		User UserRoleUpdate `json:"user"`
+	}{
+		User: update,
+	}
+
+	body, err := z.client.Put(ctx, fmt.Sprintf("/users/%d.json", userID), data)
+	if err != nil {
+		return zendesk.User{}, err
+	}
+
+	err = json.Unmarshal(body, &result)
+	if err != nil {
+		return zendesk.User{}, err
+	}
+
+	return result.User, nil
+}
+
+// FindUserByExternalID returns the user with the given external ID.
+//
+// Zendesk API docs: https://developer.zendesk.com/api-reference/ticketing/users/users/#search-users
+func (z *ZendeskClient) FindUserByExternalID(ctx context.Context, externalID string) (zendesk.User, error) {
+	users, _, err := z.client.SearchUsers(ctx, &zendesk.SearchUsersOptions{
+		ExternalIDs: externalID,
+	})
+	if err != nil {
+		return zendesk.User{}, err
+	}
+
+	for _, user := range users {
+		if user.ExternalID == externalID {
+			return user, nil
+		}
+	}
+
+	return zendesk.User{}, fmt.Errorf("user with external id %q not found", externalID)
+}
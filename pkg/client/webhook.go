@@ -0,0 +1,96 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"net/url"
+	"strconv"
+
+	"github.com/nukosuke/go-zendesk/zendesk"
+)
+
+const webhooksPageSize = 100
+
+// ListWebhooks returns a page of webhooks. Webhooks only support cursor pagination,
+// so the returned token is the after cursor of the next page.
+//
+// Zendesk API docs: https://developer.zendesk.com/api-reference/webhooks/webhooks-api/webhooks/#list-webhooks
+func (z *ZendeskClient) ListWebhooks(ctx context.Context, cursor string) ([]zendesk.Webhook, string, error) {
+	var (
+		nextPageToken string
+		result        struct {
+			Webhooks []zendesk.Webhook            `json:"webhooks"`
+			Meta     zendesk.CursorPaginationMeta `json:"meta"`
+		}
+	)
+
+	q := url.Values{}
+	q.Set("page[size]", strconv.Itoa(webhooksPageSize))
+	if cursor != "" {
+		q.Set("page[after]", cursor)
+	}
+
+	body, err := z.client.Get(ctx, "/webhooks?"+q.Encode())
+	if err != nil {
+		return nil, "", err
+	}
+
+	err = json.Unmarshal(body, &result)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if result.Meta.HasMore {
+		nextPageToken = result.Meta.AfterCursor
+	}
+
+	return result.Webhooks, nextPageToken, nil
+}
+
+// WebhookHasSigningSecret reports whether a webhook signs its requests. Listings never include the signing
+// secret, so it is fetched on its own, and only its presence is returned.
+//
+// Zendesk API docs: https://developer.zendesk.com/api-reference/webhooks/webhooks-api/webhooks/#show-webhook-signing-secret
+func (z *ZendeskClient) WebhookHasSigningSecret(ctx context.Context, webhookID string) (bool, error) {
+	secret, err := z.client.GetWebhookSigningSecret(ctx, webhookID)
+	if IsNotFound(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return secret != nil && secret.Secret != "", nil
+}
+
+// ListTargets returns a page of legacy targets.
+//
+// Zendesk API docs: https://developer.zendesk.com/api-reference/ticketing/targets/targets/#list-targets
+func (z *ZendeskClient) ListTargets(ctx context.Context, pageToken int) ([]zendesk.Target, string, error) {
+	var (
+		nextPageToken string
+		result        struct {
+			Targets []zendesk.Target `json:"targets"`
+			zendesk.Page
+		}
+	)
+
+	body, err := z.client.Get(ctx, withPage("/targets.json", pageToken))
+	if err != nil {
+		return nil, "", err
+	}
+
+	err = json.Unmarshal(body, &result)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if result.NextPage != nil {
+		nextPageToken, err = parseNextPage(*result.NextPage)
+		if err != nil {
+			return nil, "", err
+		}
+	}
+
+	return result.Targets, nextPageToken, nil
+}
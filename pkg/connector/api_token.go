@@ -0,0 +1,149 @@
+package connector
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	"github.com/conductorone/baton-zendesk/pkg/client"
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type apiTokenResourceType struct {
+	resourceType *v2.ResourceType
+	client       *client.ZendeskClient
+}
+
+func (a *apiTokenResourceType) ResourceType(_ context.Context) *v2.ResourceType {
+	return a.resourceType
+}
+
+// List returns all the API tokens of the account as secret resources.
+func (a *apiTokenResourceType) List(ctx context.Context, parentId *v2.ResourceId, pToken *pagination.Token) ([]*v2.Resource, string, annotations.Annotations, error) {
+	var (
+		pageToken int
+		err       error
+		ret       []*v2.Resource
+	)
+	if pToken.Token != "" {
+		pageToken, err = strconv.Atoi(pToken.Token)
+		if err != nil {
+			return nil, "", nil, err
+		}
+	}
+
+	tokens, nextPageToken, err := a.client.ListAPITokens(ctx, pageToken)
+	if err != nil {
+		return nil, "", nil, fmt.Errorf("zendesk-connector: failed to list api tokens: %w", err)
+	}
+
+	for _, token := range tokens {
+		tokenCopy := token
+		res, err := getAPITokenResource(&tokenCopy, a.client.IsCurrentAPIToken(tokenCopy), resourceTypeAPIToken)
+		if err != nil {
+			return nil, "", nil, err
+		}
+
+		ret = append(ret, res)
+	}
+
+	return ret, nextPageToken, nil, nil
+}
+
+// Entitlements always returns an empty slice for API tokens.
+func (a *apiTokenResourceType) Entitlements(_ context.Context, _ *v2.Resource, _ *pagination.Token) ([]*v2.Entitlement, string, annotations.Annotations, error) {
+	return nil, "", nil, nil
+}
+
+// Grants always returns an empty slice for API tokens.
+func (a *apiTokenResourceType) Grants(_ context.Context, _ *v2.Resource, _ *pagination.Token) ([]*v2.Grant, string, annotations.Annotations, error) {
+	return nil, "", nil, nil
+}
+
+// Rotate replaces an API token with a new one carrying the same description and deletes the old one.
+// If the old token cannot be deleted, the new one is deleted again. The token the connector authenticates
+// with, or any token that masking leaves indistinguishable from it, is never rotated.
+func (a *apiTokenResourceType) Rotate(ctx context.Context, resourceId *v2.ResourceId, _ *v2.CredentialOptions) ([]*v2.PlaintextData, annotations.Annotations, error) {
+	l := ctxzap.Extract(ctx)
+	if resourceId.ResourceType != resourceTypeAPIToken.Id {
+		return nil, nil, fmt.Errorf("zendesk-connector: only api tokens can be rotated")
+	}
+
+	tokenID, err := strconv.ParseInt(resourceId.Resource, 10, 64)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	token, err := a.client.GetAPIToken(ctx, tokenID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("zendesk-connector: failed to fetch api token: %w", err)
+	}
+
+	if a.client.IsCurrentAPIToken(token) {
+		l.Warn("zendesk-connector: refusing to rotate the api token used by the connector",
+			zap.Int64("TokenID", token.ID),
+		)
+		return nil, nil, status.Errorf(codes.FailedPrecondition, "zendesk-connector: api token %d may be used by the connector and cannot be rotated", token.ID)
+	}
+
+	newToken, err := a.client.CreateAPIToken(ctx, token.Description)
+	if err != nil {
+		return nil, nil, fmt.Errorf("zendesk-connector: failed to create api token: %w", err)
+	}
+
+	err = a.client.DeleteAPIToken(ctx, token.ID)
+	if err != nil {
+		// The old token still works, so the replacement is deleted to leave the account as it was.
+		rollbackErr := a.client.DeleteAPIToken(ctx, newToken.ID)
+		if rollbackErr == nil {
+			return nil, nil, fmt.Errorf("zendesk-connector: failed to delete api token %d: %w", token.ID, err)
+		}
+
+		// The replacement could not be deleted either, so both tokens are valid and have to be cleaned up by hand.
+		l.Error("zendesk-connector: replacement api token created but old token was not deleted",
+			zap.Int64("TokenID", token.ID),
+			zap.Int64("NewTokenID", newToken.ID),
+			zap.Error(err),
+			zap.NamedError("rollback_error", rollbackErr),
+		)
+		return nil, nil, status.Errorf(codes.Internal,
+			"zendesk-connector: failed to delete api token %d (%v) and its replacement %d (%v): both tokens are still valid",
+			token.ID, err, newToken.ID, rollbackErr)
+	}
+
+	l.Warn("API token has been rotated.",
+		zap.Int64("TokenID", token.ID),
+		zap.Int64("NewTokenID", newToken.ID),
+	)
+
+	return []*v2.PlaintextData{
+		{
+			Name:        "api_token",
+			Description: fmt.Sprintf("Zendesk API token %d", newToken.ID),
+			Bytes:       []byte(newToken.Token),
+		},
+	}, nil, nil
+}
+
+// RotateCapabilityDetails reports that rotation takes no password, since Zendesk generates the value of new tokens.
+func (a *apiTokenResourceType) RotateCapabilityDetails(_ context.Context) (*v2.CredentialDetailsCredentialRotation, annotations.Annotations, error) {
+	return &v2.CredentialDetailsCredentialRotation{
+		SupportedCredentialOptions: []v2.CapabilityDetailCredentialOption{
+			v2.CapabilityDetailCredentialOption_CAPABILITY_DETAIL_CREDENTIAL_OPTION_NO_PASSWORD,
+		},
+		PreferredCredentialOption: v2.CapabilityDetailCredentialOption_CAPABILITY_DETAIL_CREDENTIAL_OPTION_NO_PASSWORD,
+	}, nil, nil
+}
+
+func apiTokenBuilder(c *client.ZendeskClient) *apiTokenResourceType {
+	return &apiTokenResourceType{
+		resourceType: resourceTypeAPIToken,
+		client:       c,
+	}
+}
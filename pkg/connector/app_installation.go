@@ -0,0 +1,222 @@
+package connector
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	ent "github.com/conductorone/baton-sdk/pkg/types/entitlement"
+	"github.com/conductorone/baton-sdk/pkg/types/grant"
+	"github.com/conductorone/baton-zendesk/pkg/client"
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
+)
+
+const appInstallationAccessEntitlement = "access"
+
+type appInstallationResourceType struct {
+	resourceType *v2.ResourceType
+	client       *client.ZendeskClient
+	members      *teamMemberFilter
+}
+
+func (a *appInstallationResourceType) ResourceType(_ context.Context) *v2.ResourceType {
+	return a.resourceType
+}
+
+// List returns all the installed apps as app resources.
+func (a *appInstallationResourceType) List(ctx context.Context, parentId *v2.ResourceId, _ *pagination.Token) ([]*v2.Resource, string, annotations.Annotations, error) {
+	var ret []*v2.Resource
+	installations, err := a.client.ListAppInstallations(ctx)
+	if err != nil {
+		return nil, "", nil, fmt.Errorf("zendesk-connector: failed to list app installations: %w", err)
+	}
+
+	for _, installation := range installations {
+		installationCopy := installation
+		res, err := getAppInstallationResource(&installationCopy, resourceTypeAppInstallation)
+		if err != nil {
+			return nil, "", nil, err
+		}
+
+		ret = append(ret, res)
+	}
+
+	return ret, "", nil, nil
+}
+
+func (a *appInstallationResourceType) Entitlements(_ context.Context, resource *v2.Resource, _ *pagination.Token) ([]*v2.Entitlement, string, annotations.Annotations, error) {
+	return []*v2.Entitlement{
+		ent.NewPermissionEntitlement(resource, appInstallationAccessEntitlement,
+			ent.WithDisplayName(fmt.Sprintf("%s App %s", resource.DisplayName, titleCase(appInstallationAccessEntitlement))),
+			ent.WithDescription(fmt.Sprintf("Access to %s app in Zendesk", resource.DisplayName)),
+			ent.WithGrantableTo(resourceTypeRole, resourceTypeGroup),
+		),
+	}, "", nil, nil
+}
+
+// Grants returns a grant for every role and group the installation is restricted to.
+// Role grants expand to the team members holding the role, and group grants to the members of the group.
+func (a *appInstallationResourceType) Grants(ctx context.Context, resource *v2.Resource, _ *pagination.Token) ([]*v2.Grant, string, annotations.Annotations, error) {
+	var rv []*v2.Grant
+	installationID, err := strconv.ParseInt(resource.Id.Resource, 10, 64)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	installation, err := a.client.GetAppInstallation(ctx, installationID)
+	if err != nil {
+		return nil, "", nil, fmt.Errorf("zendesk-connector: failed to fetch app installation: %w", err)
+	}
+
+	for _, roleID := range installation.RoleRestrictions {
+		principalID := &v2.ResourceId{
+			ResourceType: resourceTypeRole.Id,
+			Resource:     strconv.FormatInt(roleID, 10),
+		}
+		// Role entitlements are named after the Zendesk role of the team members holding the custom role.
+		entitlementIDs := make([]string, 0, len(a.members.roles))
+		for _, role := range a.members.roleNames() {
+			entitlementIDs = append(entitlementIDs, fmt.Sprintf("%s:%d:%s", resourceTypeRole.Id, roleID, role))
+		}
+		rv = append(rv, grant.NewGrant(resource, appInstallationAccessEntitlement, principalID,
+			grant.WithAnnotation(&v2.GrantExpandable{
+				EntitlementIds: entitlementIDs,
+			}),
+		))
+	}
+
+	for _, groupID := range installation.GroupRestrictions {
+		principalID := &v2.ResourceId{
+			ResourceType: resourceTypeGroup.Id,
+			Resource:     strconv.FormatInt(groupID, 10),
+		}
+		rv = append(rv, grant.NewGrant(resource, appInstallationAccessEntitlement, principalID,
+			grant.WithAnnotation(&v2.GrantExpandable{
+				EntitlementIds: []string{fmt.Sprintf("%s:%d:%s", resourceTypeGroup.Id, groupID, memberEntitlement)},
+			}),
+		))
+	}
+
+	return rv, "", nil, nil
+}
+
+// Grant adds a role or group to the restrictions of the installation.
+func (a *appInstallationResourceType) Grant(ctx context.Context, principal *v2.Resource, entitlement *v2.Entitlement) (annotations.Annotations, error) {
+	l := ctxzap.Extract(ctx)
+	if principal.Id.ResourceType != resourceTypeRole.Id && principal.Id.ResourceType != resourceTypeGroup.Id {
+		l.Warn(
+			"zendesk-connector: only roles and groups can be granted app access",
+			zap.String("principal_type", principal.Id.ResourceType),
+			zap.String("principal_id", principal.Id.Resource),
+		)
+		return nil, fmt.Errorf("zendesk-connector: only roles and groups can be granted app access")
+	}
+
+	principalID, err := strconv.ParseInt(principal.Id.Resource, 10, 64)
+	if err != nil {
+		return nil, err
+	}
+
+	installationID, err := strconv.ParseInt(entitlement.Resource.Id.Resource, 10, 64)
+	if err != nil {
+		return nil, err
+	}
+
+	installation, err := a.client.GetAppInstallation(ctx, installationID)
+	if err != nil {
+		return nil, fmt.Errorf("zendesk-connector: failed to fetch app installation: %w", err)
+	}
+
+	roleRestrictions := append(make([]int64, 0, len(installation.RoleRestrictions)+1), installation.RoleRestrictions...)
+	groupRestrictions := append(make([]int64, 0, len(installation.GroupRestrictions)+1), installation.GroupRestrictions...)
+	switch principal.Id.ResourceType {
+	case resourceTypeRole.Id:
+		roleRestrictions = appendMissingID(roleRestrictions, principalID)
+	case resourceTypeGroup.Id:
+		groupRestrictions = appendMissingID(groupRestrictions, principalID)
+	}
+
+	_, err = a.client.UpdateAppInstallationRestrictions(ctx, installationID, roleRestrictions, groupRestrictions)
+	if err != nil {
+		return nil, fmt.Errorf("zendesk-connector: failed to update app installation restrictions: %w", err)
+	}
+
+	l.Warn("App restriction has been added.",
+		zap.Int64("InstallationID", installationID),
+		zap.String("PrincipalType", principal.Id.ResourceType),
+		zap.Int64("PrincipalID", principalID),
+	)
+
+	return nil, nil
+}
+
+// Revoke removes a role or group from the restrictions of the installation.
+// Removing the last restriction would make the app available to everyone, so it is refused.
+func (a *appInstallationResourceType) Revoke(ctx context.Context, grant *v2.Grant) (annotations.Annotations, error) {
+	l := ctxzap.Extract(ctx)
+
+	entitlement := grant.Entitlement
+	principal := grant.Principal
+
+	if principal.Id.ResourceType != resourceTypeRole.Id && principal.Id.ResourceType != resourceTypeGroup.Id {
+		l.Warn(
+			"zendesk-connector: only roles and groups can have app access revoked",
+			zap.String("principal_type", principal.Id.ResourceType),
+			zap.String("principal_id", principal.Id.Resource),
+		)
+		return nil, fmt.Errorf("zendesk-connector: only roles and groups can have app access revoked")
+	}
+
+	principalID, err := strconv.ParseInt(principal.Id.Resource, 10, 64)
+	if err != nil {
+		return nil, err
+	}
+
+	installationID, err := strconv.ParseInt(entitlement.Resource.Id.Resource, 10, 64)
+	if err != nil {
+		return nil, err
+	}
+
+	installation, err := a.client.GetAppInstallation(ctx, installationID)
+	if err != nil {
+		return nil, fmt.Errorf("zendesk-connector: failed to fetch app installation: %w", err)
+	}
+
+	roleRestrictions := installation.RoleRestrictions
+	groupRestrictions := installation.GroupRestrictions
+	switch principal.Id.ResourceType {
+	case resourceTypeRole.Id:
+		roleRestrictions = removeID(roleRestrictions, principalID)
+	case resourceTypeGroup.Id:
+		groupRestrictions = removeID(groupRestrictions, principalID)
+	}
+
+	if len(roleRestrictions) == 0 && len(groupRestrictions) == 0 {
+		return nil, fmt.Errorf("zendesk-connector: revoking the last restriction would make app installation %d available to everyone", installationID)
+	}
+
+	_, err = a.client.UpdateAppInstallationRestrictions(ctx, installationID, roleRestrictions, groupRestrictions)
+	if err != nil {
+		return nil, fmt.Errorf("zendesk-connector: failed to update app installation restrictions: %w", err)
+	}
+
+	l.Warn("App restriction has been removed.",
+		zap.Int64("InstallationID", installationID),
+		zap.String("PrincipalType", principal.Id.ResourceType),
+		zap.Int64("PrincipalID", principalID),
+	)
+
+	return nil, nil
+}
+
+func appInstallationBuilder(c *client.ZendeskClient, members *teamMemberFilter) *appInstallationResourceType {
+	return &appInstallationResourceType{
+		resourceType: resourceTypeAppInstallation,
+		client:       c,
+		members:      members,
+	}
+}
@@ -0,0 +1,380 @@
+package connector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/conductorone/baton-zendesk/pkg/client"
+	"github.com/nukosuke/go-zendesk/zendesk"
+)
+
+const (
+	// batchWindow is how long a grant or revoke waits for others to join its bulk job. A single grant or revoke
+	// waits for it as well, since whether others follow is not known when it is submitted.
+	batchWindow = 500 * time.Millisecond
+	// batchMaxSize is the most memberships Zendesk accepts in a single bulk job.
+	batchMaxSize = 100
+	// jobPollInterval is how often the status of a bulk job is checked.
+	jobPollInterval = time.Second
+	// jobTimeout is how long a bulk job may stay queued or running before its items fail. The grants and revokes
+	// waiting on it may outlive their own calls, so the job is not waited on forever.
+	jobTimeout = 10 * time.Minute
+)
+
+// errMembershipExists is reported to a grant whose user is already a member.
+var errMembershipExists = errors.New("zendesk-connector: membership already exists")
+
+// membershipBatcher coalesces the group and organization grants and revokes made within a short window into
+// bulk jobs, and reports the outcome of each membership back to the call that requested it.
+type membershipBatcher struct {
+	client     *client.ZendeskClient
+	jobTimeout time.Duration
+
+	addGroups    *batchQueue
+	removeGroups *batchQueue
+	addOrgs      *batchQueue
+	removeOrgs   *batchQueue
+}
+
+func newMembershipBatcher(c *client.ZendeskClient) *membershipBatcher {
+	b := &membershipBatcher{client: c, jobTimeout: jobTimeout}
+	b.addGroups = newBatchQueue(b.createGroupMemberships)
+	b.removeGroups = newBatchQueue(b.destroyGroupMemberships)
+	b.addOrgs = newBatchQueue(b.createOrganizationMemberships)
+	b.removeOrgs = newBatchQueue(b.destroyOrganizationMemberships)
+
+	return b
+}
+
+// batchItem is a membership of a user in a group or organization to create or delete. The ID of a membership
+// to delete is set when the revoked grant recorded it, which saves looking it up.
+type batchItem struct {
+	membership
+	membershipID int64
+	done         chan batchResult
+}
+
+// batchResult is the outcome of an item, along with the ID of the membership created or found.
+type batchResult struct {
+	membershipID int64
+	err          error
+}
+
+// flushFunc processes a batch of items, and returns the result of each item at the same index.
+type flushFunc func(ctx context.Context, items []batchItem) []batchResult
+
+// batchQueue collects items until the batch window elapses or the batch is full, and flushes them together.
+type batchQueue struct {
+	flush flushFunc
+
+	mu      sync.Mutex
+	ctx     context.Context
+	pending []batchItem
+	timer   *time.Timer
+}
+
+func newBatchQueue(flush flushFunc) *batchQueue {
+	return &batchQueue{flush: flush}
+}
+
+// submit adds a membership to the next batch and waits for the outcome of its bulk job. It returns the ID of the
+// membership, if known.
+func (q *batchQueue) submit(ctx context.Context, parentID int64, userID int64, membershipID int64) (int64, error) {
+	item := batchItem{
+		membership:   membership{parentID: parentID, userID: userID},
+		membershipID: membershipID,
+		done:         make(chan batchResult, 1),
+	}
+
+	q.mu.Lock()
+	if len(q.pending) == 0 {
+		// The bulk job is shared with later calls, so it is not cancelled with the call that started the batch.
+		q.ctx = context.WithoutCancel(ctx)
+		q.timer = time.AfterFunc(batchWindow, q.flushPending)
+	}
+	q.pending = append(q.pending, item)
+	full := len(q.pending) >= batchMaxSize
+	q.mu.Unlock()
+
+	if full {
+		q.flushPending()
+	}
+
+	select {
+	case result := <-item.done:
+		return result.membershipID, result.err
+	case <-ctx.Done():
+		return 0, ctx.Err()
+	}
+}
+
+// flushPending sends the pending items, if the timer or a full batch did not already.
+func (q *batchQueue) flushPending() {
+	q.mu.Lock()
+	items := q.pending
+	ctx := q.ctx
+	q.pending = nil
+	if q.timer != nil {
+		q.timer.Stop()
+		q.timer = nil
+	}
+	q.mu.Unlock()
+
+	if len(items) == 0 {
+		return
+	}
+
+	results := q.flush(ctx, items)
+	for i, item := range items {
+		item.done <- results[i]
+	}
+}
+
+// jobResults waits for a bulk job and records the result of each item of the batch that was sent. Items of bulk creates
+// are matched by their index in the request, which sent maps to the index of the item, and items of bulk deletes
+// by their membership ID, which ids maps to the index of the item. Items the job does not report fail, and so do
+// all of them when the job is not done within the job timeout.
+func (b *membershipBatcher) jobResults(ctx context.Context, job client.JobStatus, jobErr error, sent []int, ids map[int64]int, results []batchResult) []batchResult {
+	if jobErr == nil {
+		jobID := job.ID
+		waitCtx, cancel := context.WithTimeout(ctx, b.jobTimeout)
+		job, jobErr = b.client.WaitForJobStatus(waitCtx, job, jobPollInterval)
+		cancel()
+		if errors.Is(jobErr, context.DeadlineExceeded) && ctx.Err() == nil {
+			jobErr = fmt.Errorf("zendesk-connector: job %s was not done within %s: %w", jobID, b.jobTimeout, jobErr)
+		}
+	}
+	if jobErr != nil {
+		for _, i := range sent {
+			results[i].err = jobErr
+		}
+		return results
+	}
+
+	reported := make(map[int]bool, len(sent))
+	for _, result := range job.Results {
+		i := -1
+		if ids != nil {
+			if j, ok := ids[result.ID]; ok {
+				i = j
+			}
+		} else if result.Index != nil && *result.Index >= 0 && *result.Index < len(sent) {
+			i = sent[*result.Index]
+		}
+		if i < 0 {
+			continue
+		}
+		reported[i] = true
+
+		results[i].err = result.Err()
+		if result.Error == "RecordNotFound" {
+			results[i].err = client.ErrMembershipNotFound
+		}
+		if !result.Failed() {
+			results[i].membershipID = result.ID
+		}
+	}
+
+	// Items the completed job is silent about cannot be assumed to have been processed.
+	for _, i := range sent {
+		if !reported[i] {
+			results[i].err = fmt.Errorf("zendesk-connector: job %s did not report the outcome of the membership", job.ID)
+		}
+	}
+
+	return results
+}
+
+// membershipIDs looks up the IDs of the memberships of the items, parent by parent, keyed by membership.
+// Items whose ID is already known are not looked up, and items whose lookup failed get the error.
+func membershipIDs(
+	ctx context.Context,
+	items []batchItem,
+	lookup func(ctx context.Context, parentID int64, userIDs []int64) (map[int64]int64, error),
+	results []batchResult,
+) map[membership]int64 {
+	ids := make(map[membership]int64, len(items))
+	userIDs := make(map[int64][]int64)
+	for _, item := range items {
+		if item.membershipID != 0 {
+			ids[item.membership] = item.membershipID
+			continue
+		}
+		userIDs[item.parentID] = append(userIDs[item.parentID], item.userID)
+	}
+
+	for parentID, users := range userIDs {
+		found, err := lookup(ctx, parentID, users)
+		if err != nil {
+			for i, item := range items {
+				if item.parentID == parentID && item.membershipID == 0 {
+					results[i].err = err
+				}
+			}
+			continue
+		}
+
+		for userID, id := range found {
+			ids[membership{parentID: parentID, userID: userID}] = id
+		}
+	}
+
+	return ids
+}
+
+func (b *membershipBatcher) createGroupMemberships(ctx context.Context, items []batchItem) []batchResult {
+	return b.createMemberships(ctx, items, b.client.GroupMembershipIDs, func(ctx context.Context, items []batchItem) (client.JobStatus, error) {
+		memberships := make([]zendesk.GroupMembership, 0, len(items))
+		for _, item := range items {
+			memberships = append(memberships, zendesk.GroupMembership{GroupID: item.parentID, UserID: item.userID})
+		}
+
+		job, err := b.client.CreateManyGroupMemberships(ctx, memberships)
+		if err != nil {
+			return client.JobStatus{}, fmt.Errorf("zendesk-connector: failed to create group memberships: %w", err)
+		}
+
+		return job, nil
+	})
+}
+
+func (b *membershipBatcher) createOrganizationMemberships(ctx context.Context, items []batchItem) []batchResult {
+	return b.createMemberships(ctx, items, b.client.OrganizationMembershipIDs, func(ctx context.Context, items []batchItem) (client.JobStatus, error) {
+		memberships := make([]zendesk.OrganizationMembership, 0, len(items))
+		for _, item := range items {
+			memberships = append(memberships, zendesk.OrganizationMembership{OrganizationID: item.parentID, UserID: item.userID})
+		}
+
+		job, err := b.client.CreateManyOrganizationMemberships(ctx, memberships)
+		if err != nil {
+			return client.JobStatus{}, fmt.Errorf("zendesk-connector: failed to create organization memberships: %w", err)
+		}
+
+		return job, nil
+	})
+}
+
+// createMemberships creates the memberships of the items in a single job. Items whose user is already a member
+// report errMembershipExists without being sent, so that retried grants succeed. A membership granted twice in
+// the batch is created once, and both grants get its outcome.
+func (b *membershipBatcher) createMemberships(
+	ctx context.Context,
+	items []batchItem,
+	lookup func(ctx context.Context, parentID int64, userIDs []int64) (map[int64]int64, error),
+	create func(ctx context.Context, items []batchItem) (client.JobStatus, error),
+) []batchResult {
+	results := make([]batchResult, len(items))
+	existing := membershipIDs(ctx, items, lookup, results)
+
+	var (
+		sent    []int
+		pending []batchItem
+	)
+	first := make(map[membership]int, len(items))
+	duplicates := make(map[int]int)
+	for i, item := range items {
+		if results[i].err != nil {
+			continue
+		}
+		if id, ok := existing[item.membership]; ok {
+			results[i] = batchResult{membershipID: id, err: errMembershipExists}
+			continue
+		}
+		if j, ok := first[item.membership]; ok {
+			duplicates[i] = j
+			continue
+		}
+		first[item.membership] = i
+		sent = append(sent, i)
+		pending = append(pending, item)
+	}
+	if len(pending) == 0 {
+		return results
+	}
+
+	job, err := create(ctx, pending)
+	results = b.jobResults(ctx, job, err, sent, nil, results)
+	for i, j := range duplicates {
+		results[i] = results[j]
+	}
+
+	return results
+}
+
+func (b *membershipBatcher) destroyGroupMemberships(ctx context.Context, items []batchItem) []batchResult {
+	return b.destroyMemberships(ctx, items, "group", b.client.GroupMembershipIDs, b.client.DestroyManyGroupMemberships)
+}
+
+func (b *membershipBatcher) destroyOrganizationMemberships(ctx context.Context, items []batchItem) []batchResult {
+	return b.destroyMemberships(ctx, items, "organization", b.client.OrganizationMembershipIDs, b.client.DestroyManyOrganizationMemberships)
+}
+
+// destroyMemberships deletes the memberships of the items in a single job. Items whose user is not a member
+// report client.ErrMembershipNotFound without being sent, so that retried revokes succeed. The membership ID a
+// grant recorded is stale once the user was removed and added again, so items whose recorded membership was not
+// found are looked up by user before they report it.
+func (b *membershipBatcher) destroyMemberships(
+	ctx context.Context,
+	items []batchItem,
+	parentType string,
+	lookup func(ctx context.Context, parentID int64, userIDs []int64) (map[int64]int64, error),
+	destroy func(ctx context.Context, membershipIDs []int64) (client.JobStatus, error),
+) []batchResult {
+	results := make([]batchResult, len(items))
+	existing := membershipIDs(ctx, items, lookup, results)
+
+	var (
+		sent []int
+		send []int64
+	)
+	ids := make(map[int64]int, len(items))
+	for i, item := range items {
+		if results[i].err != nil {
+			continue
+		}
+
+		id, ok := existing[item.membership]
+		if !ok {
+			results[i].err = client.ErrMembershipNotFound
+			continue
+		}
+		if _, ok := ids[id]; ok {
+			// The same membership was revoked twice in the batch, and the other revoke reports the outcome.
+			results[i].err = client.ErrMembershipNotFound
+			continue
+		}
+		ids[id] = i
+		sent = append(sent, i)
+		send = append(send, id)
+	}
+	if len(send) == 0 {
+		return results
+	}
+
+	job, err := destroy(ctx, send)
+	if err != nil {
+		err = fmt.Errorf("zendesk-connector: failed to delete %s memberships: %w", parentType, err)
+	}
+	results = b.jobResults(ctx, job, err, sent, ids, results)
+
+	var (
+		stale  []int
+		lookUp []batchItem
+	)
+	for _, i := range sent {
+		if items[i].membershipID != 0 && errors.Is(results[i].err, client.ErrMembershipNotFound) {
+			stale = append(stale, i)
+			lookUp = append(lookUp, batchItem{membership: items[i].membership})
+		}
+	}
+	if len(lookUp) > 0 {
+		for j, result := range b.destroyMemberships(ctx, lookUp, parentType, lookup, destroy) {
+			results[stale[j]] = result
+		}
+	}
+
+	return results
+}
@@ -3,6 +3,7 @@ package connector
 import (
 	"context"
 	"io"
+	"net/http"
 
 	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
 	"github.com/conductorone/baton-sdk/pkg/annotations"
@@ -11,18 +12,120 @@ import (
 )
 
 type Connector struct {
-	orgs          []string
-	zendeskClient *client.ZendeskClient
+	orgs                 []string
+	groups               *groupFilter
+	userTraits           *userTraitConfig
+	teamMembers          *teamMemberFilter
+	signOutOnRoleRestore bool
+	defaultRole          *userRole
+	customFields         *customFieldConfig
+	tagPrefixes          []string
+	tagIncludeOrgs       bool
+	userQuery            string
+	syncSessions         bool
+	orgQuery             string
+	groupPrefetch        *prefetcher
+	orgPrefetch          *prefetcher
+	sweep                *membershipSweep
+	batch                *membershipBatcher
+	users                *userDirectory
+	zendeskClient        *client.ZendeskClient
+}
+
+// Config holds the settings the connector is created with.
+type Config struct {
+	Subdomain string
+	Email     string
+	ApiToken  string
+	Orgs      []string
+	// UserQuery limits team members to the users matching a Zendesk search query, for example role:agent.
+	UserQuery string
+	// OrgQuery limits organizations to those matching a Zendesk search query, for example tags:managed.
+	OrgQuery string
+	// Groups limits syncing and provisioning to the groups with these names or IDs.
+	Groups []string
+	// GroupNameRegex limits syncing and provisioning to the groups whose name matches, in addition to Groups.
+	GroupNameRegex string
+	// BaseURL overrides the Zendesk API endpoint, for example to point at a fake server in tests.
+	BaseURL string
+	// RecordHTTPDir saves every request and response pair in the directory, without credentials or secrets.
+	RecordHTTPDir string
+	// RecordRedactPII redacts names, emails and other personal data from recordings.
+	RecordRedactPII bool
+	// ReplayHTTPDir answers requests with the pairs recorded in the directory, without network access.
+	ReplayHTTPDir string
+	// Concurrency is the number of requests made in parallel to prefetch group and org memberships.
+	// It shrinks automatically when the rate limit quota runs low.
+	Concurrency int
+	// MembershipSweep lists the group and organization memberships of the whole account once per sync,
+	// instead of listing the members of every group and organization.
+	MembershipSweep bool
+	// BatchProvisioning coalesces the group and organization grants and revokes made within a short window
+	// into bulk jobs, instead of creating and deleting memberships one by one. Every grant and revoke then waits
+	// for the window, half a second, before its job is sent, even when no other joins it. Grants and revokes fail
+	// when their job is not done within 10 minutes.
+	BatchProvisioning bool
+	// DryRun logs the requests that would change anything in Zendesk instead of sending them.
+	DryRun bool
+
+	// SSOEnforced reports every team member as signing in through SSO when the security settings of the account
+	// cannot be read.
+	SSOEnforced bool
+	// ServiceAccountPatterns are regular expressions matched against user emails and names.
+	ServiceAccountPatterns []string
+	// ServiceAccountTags are user tags that mark service accounts.
+	ServiceAccountTags []string
+	// ServiceAccountNeverLoggedIn treats users that never signed in interactively as API-only service accounts.
+	ServiceAccountNeverLoggedIn bool
+	// ServiceAccountAPIOnlyPatterns are regular expressions matched against the emails and names of users that never signed in,
+	// one of which has to match for ServiceAccountNeverLoggedIn to classify the user as API-only.
+	ServiceAccountAPIOnlyPatterns []string
+	// TeamMemberRoles are the Zendesk roles synced as team members, agent and admin by default.
+	TeamMemberRoles []string
+	// ExcludeSuspended skips suspended team members, which are otherwise synced and reported as disabled.
+	ExcludeSuspended bool
+	// ExcludeInactive skips deleted team members that Zendesk still returns, which are otherwise synced and reported as disabled.
+	ExcludeInactive bool
+	// TeamMemberCustomRoles limits team members to those assigned one of these custom role IDs.
+	TeamMemberCustomRoles []string
+	// SignOutOnRoleRestore deletes every session of a team member when revoking a custom role restores their
+	// previous role, so that they sign in again with the restored permissions.
+	SignOutOnRoleRestore bool
+	// SyncSessions lists the active sessions of every team member, which takes one request per team member.
+	SyncSessions bool
+	// DefaultRole is restored when revoking a custom role from a team member with no recorded previous role,
+	// given as <role> or <role>:<custom role id>.
+	DefaultRole string
+	// UserFields are the keys of custom user fields copied into team member profiles.
+	UserFields []string
+	// OrganizationFields are the keys of custom organization fields listed in org descriptions.
+	OrganizationFields []string
+	// TagPrefixes enables syncing tags as groups, limited to the tags starting with one of the prefixes.
+	TagPrefixes []string
+	// TagIncludeOrgs syncs organizations carrying a tag as its members, alongside users.
+	TagIncludeOrgs bool
 }
 
 // ResourceSyncers returns a ResourceSyncer for each resource type that should be synced from the upstream service.
 func (d *Connector) ResourceSyncers(ctx context.Context) []connectorbuilder.ResourceSyncer {
-	return []connectorbuilder.ResourceSyncer{
-		groupBuilder(d.zendeskClient),
-		orgBuilder(d.zendeskClient, d.orgs),
-		roleBuilder(d.zendeskClient),
-		teamBuilder(d.zendeskClient),
+	syncers := []connectorbuilder.ResourceSyncer{
+		groupBuilder(d.zendeskClient, d.users, d.teamMembers, d.groups, d.groupPrefetch, d.sweep, d.batch),
+		orgBuilder(d.zendeskClient, d.users, d.teamMembers, d.orgs, d.customFields, d.orgQuery, d.orgPrefetch, d.sweep, d.batch),
+		roleBuilder(d.zendeskClient, d.users, d.userTraits, d.teamMembers, d.defaultRole, d.signOutOnRoleRestore),
+		teamBuilder(d.zendeskClient, d.users, d.userTraits, d.teamMembers, d.customFields, d.userQuery, d.syncSessions),
+		apiTokenBuilder(d.zendeskClient),
+		oauthClientBuilder(d.zendeskClient),
+		oauthTokenBuilder(d.zendeskClient),
+		appInstallationBuilder(d.zendeskClient, d.teamMembers),
+		webhookBuilder(d.zendeskClient),
+		targetBuilder(d.zendeskClient),
+	}
+
+	if len(d.tagPrefixes) > 0 {
+		syncers = append(syncers, tagBuilder(d.zendeskClient, d.users, d.teamMembers, d.tagPrefixes, d.tagIncludeOrgs, d.orgs, d.orgQuery))
 	}
+
+	return syncers
 }
 
 // Asset takes an input AssetRef and attempts to fetch it using the connector's authenticated http client
@@ -35,7 +138,7 @@ func (d *Connector) Asset(ctx context.Context, asset *v2.AssetRef) (string, io.R
 func (d *Connector) Metadata(ctx context.Context) (*v2.ConnectorMetadata, error) {
 	return &v2.ConnectorMetadata{
 		DisplayName: "Zendesk Connector",
-		Description: "Connector syncing users, groups, and roles from Zendesk..",
+		Description: "Connector syncing users, groups, roles, API tokens, OAuth clients and tokens, app installations, and webhooks from Zendesk.",
 	}, nil
 }
 
@@ -46,18 +149,110 @@ func (d *Connector) Validate(ctx context.Context) (annotations.Annotations, erro
 }
 
 // New returns a new instance of the connector.
-func New(ctx context.Context, zendeskOrgs []string, subdomain string, email string, apiToken string) (*Connector, error) {
-	var zc *client.ZendeskClient
-	if apiToken != "" {
-		var err error
-		zc, err = client.New(ctx, nil, subdomain, email, apiToken)
+func New(ctx context.Context, cfg *Config) (*Connector, error) {
+	var (
+		zc    *client.ZendeskClient
+		quota func() (int, int, bool)
+	)
+	if cfg.ApiToken != "" || cfg.ReplayHTTPDir != "" {
+		var opts []client.Option
+		if cfg.BaseURL != "" {
+			opts = append(opts, client.WithEndpointURL(cfg.BaseURL))
+		}
+
+		httpClient, rateLimit, err := newHTTPClient(cfg)
+		if err != nil {
+			return nil, err
+		}
+		quota = rateLimit.Quota
+
+		zc, err = client.New(ctx, httpClient, cfg.Subdomain, cfg.Email, cfg.ApiToken, opts...)
 		if err != nil {
 			return nil, err
 		}
 	}
 
+	userTraits, err := newUserTraitConfig(cfg.SSOEnforced, cfg.ServiceAccountPatterns, cfg.ServiceAccountTags, cfg.ServiceAccountNeverLoggedIn, cfg.ServiceAccountAPIOnlyPatterns)
+	if err != nil {
+		return nil, err
+	}
+
+	groups, err := newGroupFilter(cfg.Groups, cfg.GroupNameRegex)
+	if err != nil {
+		return nil, err
+	}
+
+	teamMembers, err := newTeamMemberFilter(cfg.TeamMemberRoles, cfg.ExcludeSuspended, cfg.ExcludeInactive, cfg.TeamMemberCustomRoles)
+	if err != nil {
+		return nil, err
+	}
+
+	defaultRole, err := parseUserRole(cfg.DefaultRole)
+	if err != nil {
+		return nil, err
+	}
+
+	var sweep *membershipSweep
+	if cfg.MembershipSweep {
+		sweep = newMembershipSweep(ctx, zc)
+	}
+
+	var batch *membershipBatcher
+	if cfg.BatchProvisioning {
+		batch = newMembershipBatcher(zc)
+	}
+
+	// Groups and orgs prefetch their memberships through the same pool of workers.
+	pool := newWorkerPool(cfg.Concurrency, quota)
+
 	return &Connector{
-		zendeskClient: zc,
-		orgs:          zendeskOrgs,
+		zendeskClient:        zc,
+		orgs:                 cfg.Orgs,
+		groups:               groups,
+		userTraits:           userTraits,
+		teamMembers:          teamMembers,
+		signOutOnRoleRestore: cfg.SignOutOnRoleRestore,
+		defaultRole:          defaultRole,
+		customFields:         newCustomFieldConfig(cfg.UserFields, cfg.OrganizationFields),
+		tagPrefixes:          cfg.TagPrefixes,
+		tagIncludeOrgs:       cfg.TagIncludeOrgs,
+		userQuery:            cfg.UserQuery,
+		syncSessions:         cfg.SyncSessions,
+		orgQuery:             cfg.OrgQuery,
+		groupPrefetch:        newPrefetcher(ctx, pool),
+		orgPrefetch:          newPrefetcher(ctx, pool),
+		sweep:                sweep,
+		batch:                batch,
+		users:                newUserDirectory(zc, teamMembers),
 	}, nil
 }
+
+// newHTTPClient returns the HTTP client used to reach Zendesk, recording or replaying exchanges
+// and skipping writes when configured, along with the transport tracking its rate limit quota.
+func newHTTPClient(cfg *Config) (*http.Client, *client.RateLimitTransport, error) {
+	var transport http.RoundTripper = http.DefaultTransport
+
+	if cfg.ReplayHTTPDir != "" {
+		replay, err := client.NewReplayTransport(cfg.ReplayHTTPDir)
+		if err != nil {
+			return nil, nil, err
+		}
+		transport = replay
+	}
+
+	if cfg.DryRun {
+		transport = client.NewDryRunTransport(transport)
+	}
+
+	if cfg.RecordHTTPDir != "" {
+		recorder, err := client.NewRecordingTransport(transport, cfg.RecordHTTPDir, cfg.RecordRedactPII)
+		if err != nil {
+			return nil, nil, err
+		}
+		transport = recorder
+	}
+
+	rateLimit := client.NewRateLimitTransport(transport)
+
+	return &http.Client{Transport: rateLimit}, rateLimit, nil
+}
@@ -0,0 +1,1549 @@
+package connector
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/connectorbuilder"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	rs "github.com/conductorone/baton-sdk/pkg/types/resource"
+	"github.com/nukosuke/go-zendesk/zendesk"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/conductorone/baton-zendesk/pkg/client"
+	"github.com/conductorone/baton-zendesk/pkg/zendesktest"
+)
+
+// newTestConnector starts a fake Zendesk server seeded with the default fixtures and returns a connector using it.
+func newTestConnector(t *testing.T, configure func(*Config)) (*Connector, *zendesktest.Server) {
+	t.Helper()
+
+	server := zendesktest.NewServer(zendesktest.DefaultFixtures())
+	t.Cleanup(server.Close)
+
+	cfg := &Config{
+		Subdomain: "example",
+		Email:     "admin@example.com",
+		ApiToken:  "connector-api-token",
+		BaseURL:   server.EndpointURL(),
+	}
+	if configure != nil {
+		configure(cfg)
+	}
+
+	c, err := New(context.Background(), cfg)
+	if err != nil {
+		t.Fatalf("creating connector: %v", err)
+	}
+
+	return c, server
+}
+
+func findSyncer(t *testing.T, c *Connector, resourceType *v2.ResourceType) connectorbuilder.ResourceSyncer {
+	t.Helper()
+
+	ctx := context.Background()
+	for _, syncer := range c.ResourceSyncers(ctx) {
+		if syncer.ResourceType(ctx).Id == resourceType.Id {
+			return syncer
+		}
+	}
+
+	t.Fatalf("no syncer for resource type %s", resourceType.Id)
+	return nil
+}
+
+func listAll(t *testing.T, syncer connectorbuilder.ResourceSyncer) []*v2.Resource {
+	t.Helper()
+
+	var (
+		ctx   = context.Background()
+		rv    []*v2.Resource
+		token string
+	)
+	for {
+		resources, next, _, err := syncer.List(ctx, nil, &pagination.Token{Token: token})
+		if err != nil {
+			t.Fatalf("listing %s: %v", syncer.ResourceType(ctx).Id, err)
+		}
+		rv = append(rv, resources...)
+		if next == "" {
+			return rv
+		}
+		if next == token {
+			t.Fatalf("listing %s: page token %q repeats", syncer.ResourceType(ctx).Id, next)
+		}
+		token = next
+	}
+}
+
+func entitlementsAll(t *testing.T, syncer connectorbuilder.ResourceSyncer, resource *v2.Resource) []*v2.Entitlement {
+	t.Helper()
+
+	var (
+		ctx   = context.Background()
+		rv    []*v2.Entitlement
+		token string
+	)
+	for {
+		entitlements, next, _, err := syncer.Entitlements(ctx, resource, &pagination.Token{Token: token})
+		if err != nil {
+			t.Fatalf("listing entitlements of %s: %v", resource.Id.Resource, err)
+		}
+		rv = append(rv, entitlements...)
+		if next == "" {
+			return rv
+		}
+		if next == token {
+			t.Fatalf("listing entitlements of %s: page token %q repeats", resource.Id.Resource, next)
+		}
+		token = next
+	}
+}
+
+func grantsAll(t *testing.T, syncer connectorbuilder.ResourceSyncer, resource *v2.Resource) []*v2.Grant {
+	t.Helper()
+
+	var (
+		ctx   = context.Background()
+		rv    []*v2.Grant
+		token string
+	)
+	for {
+		grants, next, _, err := syncer.Grants(ctx, resource, &pagination.Token{Token: token})
+		if err != nil {
+			t.Fatalf("listing grants of %s: %v", resource.Id.Resource, err)
+		}
+		rv = append(rv, grants...)
+		if next == "" {
+			return rv
+		}
+		if next == token {
+			t.Fatalf("listing grants of %s: page token %q repeats", resource.Id.Resource, next)
+		}
+		token = next
+	}
+}
+
+func findResource(t *testing.T, resources []*v2.Resource, id string) *v2.Resource {
+	t.Helper()
+
+	for _, resource := range resources {
+		if resource.Id.Resource == id {
+			return resource
+		}
+	}
+
+	t.Fatalf("resource %s not found", id)
+	return nil
+}
+
+// grantedTeamMembers counts the grants of an entitlement per team member.
+func grantedTeamMembers(grants []*v2.Grant, entitlementSlug string) map[string]int {
+	rv := make(map[string]int)
+	for _, g := range grants {
+		if g.Principal.Id.ResourceType != resourceTypeTeam.Id || !strings.HasSuffix(g.Entitlement.Id, ":"+entitlementSlug) {
+			continue
+		}
+		rv[g.Principal.Id.Resource]++
+	}
+
+	return rv
+}
+
+func TestResourceSyncers(t *testing.T) {
+	c, server := newTestConnector(t, nil)
+	// A tiny page size makes every paginated endpoint return several pages.
+	server.PageSize = 1
+
+	ctx := context.Background()
+	for _, syncer := range c.ResourceSyncers(ctx) {
+		syncer := syncer
+		t.Run(syncer.ResourceType(ctx).Id, func(t *testing.T) {
+			for _, resource := range listAll(t, syncer) {
+				entitlementsAll(t, syncer, resource)
+				for _, g := range grantsAll(t, syncer, resource) {
+					if g.Principal.Id.Resource == "0" {
+						t.Errorf("grant %s has a principal with ID 0", g.Id)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestOrgListHasNoDuplicates(t *testing.T) {
+	c, server := newTestConnector(t, nil)
+	server.PageSize = 2
+
+	orgs := listAll(t, findSyncer(t, c, resourceTypeOrg))
+
+	seen := make(map[string]bool)
+	for _, org := range orgs {
+		if seen[org.Id.Resource] {
+			t.Errorf("org %s is listed more than once", org.Id.Resource)
+		}
+		seen[org.Id.Resource] = true
+	}
+	if len(seen) != 3 {
+		t.Errorf("got %d orgs, want 3", len(seen))
+	}
+}
+
+func TestOrgCustomFields(t *testing.T) {
+	c, server := newTestConnector(t, func(cfg *Config) {
+		cfg.OrganizationFields = []string{"region", "seats"}
+	})
+	// Every field definition is on its own page.
+	server.PageSize = 1
+
+	acme := findResource(t, listAll(t, findSyncer(t, c, resourceTypeOrg)), "100")
+	if want := "region: EU; seats: 25"; acme.Description != want {
+		t.Errorf("org 100 has description %q, want %q", acme.Description, want)
+	}
+}
+
+func TestOrgListFiltersByNameAndExternalID(t *testing.T) {
+	c, server := newTestConnector(t, func(cfg *Config) {
+		cfg.Orgs = []string{"crm-acme", "Globex"}
+	})
+	server.PageSize = 1
+
+	orgs := listAll(t, findSyncer(t, c, resourceTypeOrg))
+	if len(orgs) != 2 {
+		t.Fatalf("got %d orgs, want 2", len(orgs))
+	}
+	if got := server.Requests("GET /api/v2/organizations.json"); got != 0 {
+		t.Errorf("listed every org %d times, want the orgs filter searched instead", got)
+	}
+
+	acme := findResource(t, orgs, "100")
+	if got := acme.GetExternalId().GetId(); got != "crm-acme" {
+		t.Errorf("external ID of Acme is %q, want crm-acme", got)
+	}
+}
+
+func TestGroupGrants(t *testing.T) {
+	c, server := newTestConnector(t, nil)
+	server.PageSize = 1
+
+	syncer := findSyncer(t, c, resourceTypeGroup)
+	support := findResource(t, listAll(t, syncer), "10")
+
+	members := grantedTeamMembers(grantsAll(t, syncer, support), memberEntitlement)
+	if len(members) != 2 || members["1"] == 0 || members["2"] == 0 {
+		t.Errorf("got members %v of Support, want users 1 and 2", members)
+	}
+}
+
+func TestMembershipSweep(t *testing.T) {
+	c, server := newTestConnector(t, func(cfg *Config) {
+		cfg.MembershipSweep = true
+	})
+	server.PageSize = 1
+	// Spill users to a temporary file as soon as there is more than one.
+	c.sweep.spillAfter = 1
+	ctx := context.Background()
+	t.Cleanup(func() {
+		c.sweep.invalidateGroups(ctx)
+		c.sweep.invalidateOrgs(ctx)
+	})
+
+	groups := findSyncer(t, c, resourceTypeGroup)
+	support := findResource(t, listAll(t, groups), "10")
+	members := grantedTeamMembers(grantsAll(t, groups, support), memberEntitlement)
+	if len(members) != 2 || members["1"] == 0 || members["2"] == 0 {
+		t.Errorf("got members %v of Support, want users 1 and 2", members)
+	}
+
+	orgs := findSyncer(t, c, resourceTypeOrg)
+	globex := findResource(t, listAll(t, orgs), "200")
+	members = make(map[string]int)
+	membershipIDs := make(map[string]int64)
+	for _, g := range grantsAll(t, orgs, globex) {
+		members[g.Principal.Id.Resource]++
+		membershipIDs[g.Principal.Id.Resource], _ = grantMembershipID(g)
+	}
+	if len(members) != 2 || members["3"] == 0 || members["4"] == 0 {
+		t.Errorf("got members %v of Globex, want users 3 and 4", members)
+	}
+	// Swept memberships, spilled to a temporary file here, keep their IDs.
+	if membershipIDs["3"] != 2002 || membershipIDs["4"] != 2003 {
+		t.Errorf("got membership IDs %v of Globex, want 2002 and 2003", membershipIDs)
+	}
+
+	// Provisioning updates the swept memberships instead of sweeping them again.
+	sweeps := server.Requests("GET /api/v2/group_memberships.json")
+	provisioner, ok := groups.(connectorbuilder.ResourceProvisionerV2)
+	if !ok {
+		t.Fatalf("group syncer is not a provisioner")
+	}
+	entitlement := findEntitlement(t, entitlementsAll(t, groups, support), memberEntitlement)
+	_, _, err := provisioner.Grant(ctx, teamMemberResource(t, 3), entitlement)
+	if err != nil {
+		t.Fatalf("granting: %v", err)
+	}
+	_, err = provisioner.Revoke(ctx, &v2.Grant{Entitlement: entitlement, Principal: teamMemberResource(t, 1)})
+	if err != nil {
+		t.Fatalf("revoking: %v", err)
+	}
+	members = grantedTeamMembers(grantsAll(t, groups, support), memberEntitlement)
+	if len(members) != 2 || members["2"] == 0 || members["3"] == 0 {
+		t.Errorf("got members %v of Support after grant and revoke, want users 2 and 3", members)
+	}
+	if got := server.Requests("GET /api/v2/group_memberships.json") - sweeps; got != 0 {
+		t.Errorf("group memberships were listed %d more times after provisioning, want 0", got)
+	}
+}
+
+func TestMembershipIndexOutlivesInvalidation(t *testing.T) {
+	c, _ := newTestConnector(t, func(cfg *Config) {
+		cfg.MembershipSweep = true
+	})
+	c.sweep.spillAfter = 1
+	ctx := context.Background()
+
+	idx, err := c.sweep.groupIndex(ctx)
+	if err != nil {
+		t.Fatalf("sweeping groups: %v", err)
+	}
+
+	// A sync starting over drops the index while a Grants call still reads its temporary files.
+	c.sweep.invalidateGroups(ctx)
+	memberships, users, _, err := idx.page(10, 0)
+	idx.release(ctx)
+	if err != nil {
+		t.Fatalf("reading an invalidated index: %v", err)
+	}
+	if len(memberships) != 2 || len(users) != 2 {
+		t.Errorf("got memberships %v and users %v of group 10, want users 1 and 2", memberships, users)
+	}
+	if idx.memberships.file != nil || idx.users.file != nil {
+		t.Errorf("the temporary files of the index were kept after its last reader released it")
+	}
+}
+
+func TestTeamMembers(t *testing.T) {
+	c, _ := newTestConnector(t, nil)
+
+	members := listAll(t, findSyncer(t, c, resourceTypeTeam))
+	for _, member := range members {
+		if member.Id.Resource == "4" {
+			t.Errorf("end-user 4 is listed as a team member")
+		}
+	}
+
+	alice := findResource(t, members, "1")
+	trait, err := rs.GetUserTrait(alice)
+	if err != nil {
+		t.Fatalf("getting user trait: %v", err)
+	}
+	if got, _ := rs.GetProfileStringValue(trait.GetProfile(), "external_id"); got != "idp-alice" {
+		t.Errorf("external_id of Alice is %q, want idp-alice", got)
+	}
+}
+
+func TestTeamMemberInclusion(t *testing.T) {
+	c, _ := newTestConnector(t, nil)
+
+	eve := findResource(t, listAll(t, findSyncer(t, c, resourceTypeTeam)), "5")
+	trait, err := rs.GetUserTrait(eve)
+	if err != nil {
+		t.Fatalf("getting user trait: %v", err)
+	}
+	if trait.GetStatus().GetStatus() != v2.UserTrait_Status_STATUS_DISABLED {
+		t.Errorf("suspended agent 5 has status %s, want disabled", trait.GetStatus().GetStatus())
+	}
+
+	frank := findResource(t, listAll(t, findSyncer(t, c, resourceTypeTeam)), "6")
+	trait, err = rs.GetUserTrait(frank)
+	if err != nil {
+		t.Fatalf("getting user trait: %v", err)
+	}
+	if trait.GetStatus().GetStatus() != v2.UserTrait_Status_STATUS_DISABLED {
+		t.Errorf("inactive agent 6 has status %s, want disabled", trait.GetStatus().GetStatus())
+	}
+
+	c, _ = newTestConnector(t, func(cfg *Config) {
+		cfg.ExcludeSuspended = true
+		cfg.ExcludeInactive = true
+		cfg.TeamMemberRoles = []string{"agent"}
+	})
+
+	for _, member := range listAll(t, findSyncer(t, c, resourceTypeTeam)) {
+		switch member.Id.Resource {
+		case "1":
+			t.Errorf("admin 1 is listed with only agents configured")
+		case "5":
+			t.Errorf("suspended agent 5 is listed with suspended team members excluded")
+		case "6":
+			t.Errorf("inactive agent 6 is listed with inactive team members excluded")
+		}
+	}
+
+	// Group and org grants follow the configured roles, so admin 1 is granted neither.
+	groups := findSyncer(t, c, resourceTypeGroup)
+	support := findResource(t, listAll(t, groups), "10")
+	if members := grantedTeamMembers(grantsAll(t, groups, support), memberEntitlement); members["1"] != 0 || members["2"] == 0 {
+		t.Errorf("got members %v of Support, want agent 2 without admin 1", members)
+	}
+	orgs := findSyncer(t, c, resourceTypeOrg)
+	acme := findResource(t, listAll(t, orgs), "100")
+	for _, g := range grantsAll(t, orgs, acme) {
+		if g.Principal.Id.Resource == "1" {
+			t.Errorf("admin 1 is granted %s with only agents configured", g.Entitlement.Id)
+		}
+	}
+
+	c, _ = newTestConnector(t, func(cfg *Config) {
+		cfg.TeamMemberCustomRoles = []string{"500"}
+	})
+
+	members := listAll(t, findSyncer(t, c, resourceTypeTeam))
+	if len(members) != 1 || members[0].Id.Resource != "2" {
+		t.Errorf("got %d team members, want only agent 2 with custom role 500", len(members))
+	}
+}
+
+func TestGroupFilter(t *testing.T) {
+	c, server := newTestConnector(t, func(cfg *Config) {
+		cfg.Groups = []string{"11"}
+		cfg.GroupNameRegex = "^Bill"
+	})
+	ctx := context.Background()
+
+	syncer := findSyncer(t, c, resourceTypeGroup)
+	groups := listAll(t, syncer)
+	if len(groups) != 1 || groups[0].Id.Resource != "11" {
+		t.Fatalf("got %d groups, want only group 11", len(groups))
+	}
+
+	provisioner, ok := syncer.(connectorbuilder.ResourceProvisionerV2)
+	if !ok {
+		t.Fatalf("group syncer is not a provisioner")
+	}
+
+	support := &v2.Resource{Id: &v2.ResourceId{ResourceType: resourceTypeGroup.Id, Resource: "10"}, DisplayName: "Support"}
+	entitlement := findEntitlement(t, entitlementsAll(t, syncer, support), memberEntitlement)
+
+	_, _, err := provisioner.Grant(ctx, teamMemberResource(t, 3), entitlement)
+	if err == nil {
+		t.Errorf("granting membership of an ungoverned group succeeded")
+	}
+	if hasGroupMembership(server, 3, 10) {
+		t.Errorf("user 3 was added to ungoverned group 10")
+	}
+}
+
+func TestSearchQueries(t *testing.T) {
+	c, server := newTestConnector(t, func(cfg *Config) {
+		cfg.UserQuery = "role:agent"
+		cfg.OrgQuery = "tags:managed"
+	})
+	server.PageSize = 1
+
+	var members []string
+	for _, member := range listAll(t, findSyncer(t, c, resourceTypeTeam)) {
+		members = append(members, member.Id.Resource)
+	}
+	if !slices.Equal(members, []string{"2", "3", "5", "6"}) {
+		t.Errorf("got team members %v, want agents [2 3 5 6]", members)
+	}
+	// Identities are side-loaded with the team members found rather than listed per user.
+	if got := server.Requests("GET /api/v2/users/show_many.json"); got == 0 {
+		t.Errorf("team members found were not fetched with their identities")
+	}
+	if got := server.Requests("GET /api/v2/users/2/identities.json"); got != 0 {
+		t.Errorf("listed identities of team member 2 %d times, want them side-loaded", got)
+	}
+
+	orgs := listAll(t, findSyncer(t, c, resourceTypeOrg))
+	if len(orgs) != 1 || orgs[0].Id.Resource != "100" {
+		t.Errorf("got %d orgs, want only org 100 tagged managed", len(orgs))
+	}
+
+	// The team member roles narrow user queries that do not search by role.
+	c, _ = newTestConnector(t, func(cfg *Config) {
+		cfg.UserQuery = "tags:access-billing"
+		cfg.TeamMemberRoles = []string{"admin"}
+	})
+	members = nil
+	for _, member := range listAll(t, findSyncer(t, c, resourceTypeTeam)) {
+		members = append(members, member.Id.Resource)
+	}
+	if !slices.Equal(members, []string{"1"}) {
+		t.Errorf("got team members %v, want admin [1] tagged access-billing", members)
+	}
+}
+
+func TestGroupGrantAndRevoke(t *testing.T) {
+	c, server := newTestConnector(t, func(cfg *Config) {
+		cfg.SignOutOnRoleRestore = true
+	})
+	ctx := context.Background()
+
+	syncer := findSyncer(t, c, resourceTypeGroup)
+	provisioner, ok := syncer.(connectorbuilder.ResourceProvisionerV2)
+	if !ok {
+		t.Fatalf("group syncer is not a provisioner")
+	}
+
+	support := findResource(t, listAll(t, syncer), "10")
+	entitlement := findEntitlement(t, entitlementsAll(t, syncer, support), memberEntitlement)
+	principal := teamMemberResource(t, 3)
+
+	_, _, err := provisioner.Grant(ctx, principal, entitlement)
+	if err != nil {
+		t.Fatalf("granting: %v", err)
+	}
+	if !hasGroupMembership(server, 3, 10) {
+		t.Fatalf("user 3 is not a member of group 10 after grant")
+	}
+
+	_, err = provisioner.Revoke(ctx, &v2.Grant{Entitlement: entitlement, Principal: principal})
+	if err != nil {
+		t.Fatalf("revoking: %v", err)
+	}
+	if hasGroupMembership(server, 3, 10) {
+		t.Fatalf("user 3 is still a member of group 10 after revoke")
+	}
+	if signOuts := server.SignOuts(); len(signOuts) != 0 {
+		t.Errorf("revoking a group membership signed out %v", signOuts)
+	}
+}
+
+func TestDryRunDoesNotChangeMemberships(t *testing.T) {
+	c, server := newTestConnector(t, func(cfg *Config) {
+		cfg.DryRun = true
+		cfg.SignOutOnRoleRestore = true
+	})
+	ctx := context.Background()
+
+	syncer := findSyncer(t, c, resourceTypeGroup)
+	provisioner, ok := syncer.(connectorbuilder.ResourceProvisionerV2)
+	if !ok {
+		t.Fatalf("group syncer is not a provisioner")
+	}
+
+	support := findResource(t, listAll(t, syncer), "10")
+	entitlement := findEntitlement(t, entitlementsAll(t, syncer, support), memberEntitlement)
+	principal := teamMemberResource(t, 3)
+
+	_, _, err := provisioner.Grant(ctx, principal, entitlement)
+	if err != nil {
+		t.Fatalf("granting: %v", err)
+	}
+	if hasGroupMembership(server, 3, 10) {
+		t.Fatalf("dry run added user 3 to group 10")
+	}
+
+	_, err = provisioner.Revoke(ctx, &v2.Grant{Entitlement: entitlement, Principal: teamMemberResource(t, 2)})
+	if err != nil {
+		t.Fatalf("revoking: %v", err)
+	}
+	if !hasGroupMembership(server, 2, 10) {
+		t.Fatalf("dry run removed user 2 from group 10")
+	}
+	if signOuts := server.SignOuts(); len(signOuts) != 0 {
+		t.Errorf("dry run signed out %v", signOuts)
+	}
+}
+
+func TestBatchProvisioning(t *testing.T) {
+	c, server := newTestConnector(t, func(cfg *Config) {
+		cfg.BatchProvisioning = true
+	})
+	ctx := context.Background()
+
+	syncer := findSyncer(t, c, resourceTypeGroup)
+	provisioner, ok := syncer.(connectorbuilder.ResourceProvisionerV2)
+	if !ok {
+		t.Fatalf("group syncer is not a provisioner")
+	}
+
+	groups := listAll(t, syncer)
+	support := findEntitlement(t, entitlementsAll(t, syncer, findResource(t, groups, "10")), memberEntitlement)
+	billing := findEntitlement(t, entitlementsAll(t, syncer, findResource(t, groups, "11")), memberEntitlement)
+
+	alice, bob, carol := teamMemberResource(t, 1), teamMemberResource(t, 2), teamMemberResource(t, 3)
+
+	// Calls made together share bulk jobs, and each learns the outcome of its own membership.
+	// Granting an existing membership and revoking a missing one succeed without changing anything.
+	var (
+		wg    sync.WaitGroup
+		annos [5]annotations.Annotations
+		errs  [5]error
+	)
+	for i, call := range []func() (annotations.Annotations, error){
+		func() (annotations.Annotations, error) {
+			_, annos, err := provisioner.Grant(ctx, carol, support)
+			return annos, err
+		},
+		func() (annotations.Annotations, error) {
+			_, annos, err := provisioner.Grant(ctx, alice, support)
+			return annos, err
+		},
+		func() (annotations.Annotations, error) {
+			_, annos, err := provisioner.Grant(ctx, bob, billing)
+			return annos, err
+		},
+		func() (annotations.Annotations, error) {
+			return provisioner.Revoke(ctx, &v2.Grant{Entitlement: support, Principal: bob})
+		},
+		func() (annotations.Annotations, error) {
+			return provisioner.Revoke(ctx, &v2.Grant{Entitlement: billing, Principal: alice})
+		},
+	} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			annos[i], errs[i] = call()
+		}()
+	}
+	wg.Wait()
+
+	for i, err := range errs {
+		if err != nil {
+			t.Errorf("call %d: %v", i, err)
+		}
+	}
+	if !annos[1].Contains(&v2.GrantAlreadyExists{}) {
+		t.Errorf("granting an existing membership is not annotated as already existing")
+	}
+	if !annos[4].Contains(&v2.GrantAlreadyRevoked{}) {
+		t.Errorf("revoking a missing membership is not annotated as already revoked")
+	}
+	if !hasGroupMembership(server, 3, 10) || !hasGroupMembership(server, 2, 11) {
+		t.Errorf("granted memberships are missing")
+	}
+	if hasGroupMembership(server, 2, 10) {
+		t.Errorf("user 2 is still a member of group 10 after revoke")
+	}
+
+	orgSyncer := findSyncer(t, c, resourceTypeOrg)
+	orgProvisioner, ok := orgSyncer.(connectorbuilder.ResourceProvisionerV2)
+	if !ok {
+		t.Fatalf("org syncer is not a provisioner")
+	}
+
+	initech := findEntitlement(t, entitlementsAll(t, orgSyncer, findResource(t, listAll(t, orgSyncer), "300")), orgRoleMember)
+	_, _, err := orgProvisioner.Grant(ctx, teamMemberResource(t, 4), initech)
+	if err != nil {
+		t.Fatalf("granting: %v", err)
+	}
+	if !hasOrgMembership(server, 4, 300) {
+		t.Fatalf("user 4 is not a member of org 300 after grant")
+	}
+
+	_, err = orgProvisioner.Revoke(ctx, &v2.Grant{Entitlement: initech, Principal: teamMemberResource(t, 4)})
+	if err != nil {
+		t.Fatalf("revoking: %v", err)
+	}
+	if hasOrgMembership(server, 4, 300) {
+		t.Fatalf("user 4 is still a member of org 300 after revoke")
+	}
+}
+
+func TestBatchProvisioningDuplicatesAndStalledJobs(t *testing.T) {
+	c, server := newTestConnector(t, func(cfg *Config) {
+		cfg.BatchProvisioning = true
+	})
+	ctx := context.Background()
+
+	syncer := findSyncer(t, c, resourceTypeGroup)
+	provisioner, ok := syncer.(connectorbuilder.ResourceProvisionerV2)
+	if !ok {
+		t.Fatalf("group syncer is not a provisioner")
+	}
+	groups := listAll(t, syncer)
+	support := findEntitlement(t, entitlementsAll(t, syncer, findResource(t, groups, "10")), memberEntitlement)
+	billing := findEntitlement(t, entitlementsAll(t, syncer, findResource(t, groups, "11")), memberEntitlement)
+
+	// The same membership granted twice in a batch is created once, and both grants carry its ID.
+	var (
+		wg     sync.WaitGroup
+		grants [2][]*v2.Grant
+		annos  [2]annotations.Annotations
+		errs   [2]error
+	)
+	for i := range grants {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			grants[i], annos[i], errs[i] = provisioner.Grant(ctx, teamMemberResource(t, 3), support)
+		}()
+	}
+	wg.Wait()
+
+	for i := range grants {
+		if errs[i] != nil {
+			t.Fatalf("grant %d: %v", i, errs[i])
+		}
+		if annos[i].Contains(&v2.GrantAlreadyExists{}) {
+			t.Errorf("grant %d of a membership created by the batch is annotated as already existing", i)
+		}
+		if id, ok := grantMembershipID(grants[i][0]); !ok || id == 0 {
+			t.Errorf("grant %d carries no membership ID", i)
+		}
+	}
+	if got := server.Requests("POST /api/v2/group_memberships/create_many.json"); got != 1 {
+		t.Errorf("got %d bulk creates, want 1", got)
+	}
+
+	// Grants fail when their job stays queued past the job timeout.
+	server.StallJobs(true)
+	c.batch.jobTimeout = 100 * time.Millisecond
+	_, _, err := provisioner.Grant(ctx, teamMemberResource(t, 1), billing)
+	if err == nil || !strings.Contains(err.Error(), "was not done within") {
+		t.Errorf("granting through a stalled job returned %v, want a timeout", err)
+	}
+}
+
+func TestBatchProvisioningDryRun(t *testing.T) {
+	c, server := newTestConnector(t, func(cfg *Config) {
+		cfg.BatchProvisioning = true
+		cfg.DryRun = true
+	})
+	ctx := context.Background()
+
+	syncer := findSyncer(t, c, resourceTypeGroup)
+	provisioner, ok := syncer.(connectorbuilder.ResourceProvisionerV2)
+	if !ok {
+		t.Fatalf("group syncer is not a provisioner")
+	}
+	support := findEntitlement(t, entitlementsAll(t, syncer, findResource(t, listAll(t, syncer), "10")), memberEntitlement)
+
+	_, _, err := provisioner.Grant(ctx, teamMemberResource(t, 3), support)
+	if err != nil {
+		t.Fatalf("granting: %v", err)
+	}
+	if hasGroupMembership(server, 3, 10) {
+		t.Fatalf("dry run added user 3 to group 10")
+	}
+
+	_, err = provisioner.Revoke(ctx, &v2.Grant{Entitlement: support, Principal: teamMemberResource(t, 2)})
+	if err != nil {
+		t.Fatalf("revoking: %v", err)
+	}
+	if !hasGroupMembership(server, 2, 10) {
+		t.Fatalf("dry run removed user 2 from group 10")
+	}
+}
+
+func TestBatchJobResultsReportMissingItems(t *testing.T) {
+	first := 0
+	job := client.JobStatus{
+		ID:      "1",
+		Status:  client.JobStatusCompleted,
+		Results: []client.JobResult{{ID: 7, Index: &first, Success: true}},
+	}
+
+	b := &membershipBatcher{}
+	results := b.jobResults(context.Background(), job, nil, []int{0, 1}, nil, make([]batchResult, 2))
+	if results[0].err != nil || results[0].membershipID != 7 {
+		t.Errorf("got result %+v for the reported item, want membership 7", results[0])
+	}
+	if results[1].err == nil {
+		t.Errorf("the item the job does not report succeeded")
+	}
+}
+
+func TestOrgGrantAndRevoke(t *testing.T) {
+	c, server := newTestConnector(t, nil)
+	ctx := context.Background()
+
+	syncer := findSyncer(t, c, resourceTypeOrg)
+	provisioner, ok := syncer.(connectorbuilder.ResourceProvisionerV2)
+	if !ok {
+		t.Fatalf("org syncer is not a provisioner")
+	}
+
+	initech := findResource(t, listAll(t, syncer), "300")
+	entitlement := findEntitlement(t, entitlementsAll(t, syncer, initech), orgRoleMember)
+	principal := teamMemberResource(t, 4)
+
+	_, _, err := provisioner.Grant(ctx, principal, entitlement)
+	if err != nil {
+		t.Fatalf("granting: %v", err)
+	}
+	if !hasOrgMembership(server, 4, 300) {
+		t.Fatalf("user 4 is not a member of org 300 after grant")
+	}
+
+	_, err = provisioner.Revoke(ctx, &v2.Grant{Entitlement: entitlement, Principal: principal})
+	if err != nil {
+		t.Fatalf("revoking: %v", err)
+	}
+	if hasOrgMembership(server, 4, 300) {
+		t.Fatalf("user 4 is still a member of org 300 after revoke")
+	}
+}
+
+func TestGrantAndRevokeAreIdempotent(t *testing.T) {
+	c, server := newTestConnector(t, func(cfg *Config) {
+		cfg.SignOutOnRoleRestore = true
+	})
+	ctx := context.Background()
+
+	for _, tc := range []struct {
+		resourceType *v2.ResourceType
+		resourceID   string
+		slug         string
+		granted      int64
+		revoked      int64
+	}{
+		{resourceTypeGroup, "10", memberEntitlement, 1, 3},
+		{resourceTypeOrg, "100", orgRoleMember, 2, 4},
+		{resourceTypeRole, "500", "agent", 2, 3},
+	} {
+		syncer := findSyncer(t, c, tc.resourceType)
+		provisioner, ok := syncer.(connectorbuilder.ResourceProvisionerV2)
+		if !ok {
+			t.Fatalf("%s syncer is not a provisioner", tc.resourceType.Id)
+		}
+		resource := findResource(t, listAll(t, syncer), tc.resourceID)
+		entitlement := findEntitlement(t, entitlementsAll(t, syncer, resource), tc.slug)
+
+		_, annos, err := provisioner.Grant(ctx, teamMemberResource(t, tc.granted), entitlement)
+		if err != nil {
+			t.Errorf("granting %s %s again: %v", tc.resourceType.Id, tc.resourceID, err)
+		} else if !annos.Contains(&v2.GrantAlreadyExists{}) {
+			t.Errorf("granting %s %s again is not annotated as already existing", tc.resourceType.Id, tc.resourceID)
+		}
+
+		annos, err = provisioner.Revoke(ctx, &v2.Grant{Entitlement: entitlement, Principal: teamMemberResource(t, tc.revoked)})
+		if err != nil {
+			t.Errorf("revoking missing %s %s: %v", tc.resourceType.Id, tc.resourceID, err)
+		} else if !annos.Contains(&v2.GrantAlreadyRevoked{}) {
+			t.Errorf("revoking missing %s %s is not annotated as already revoked", tc.resourceType.Id, tc.resourceID)
+		}
+	}
+
+	if got := server.Requests("POST /api/v2/group_memberships.json") + server.Requests("POST /api/v2/organization_memberships.json"); got != 0 {
+		t.Errorf("%d memberships were created, want 0", got)
+	}
+	if got := server.Requests("DELETE /api/v2/group_memberships/"); got != 0 {
+		t.Errorf("an empty membership ID was deleted %d times", got)
+	}
+	if signOuts := server.SignOuts(); len(signOuts) != 0 {
+		t.Errorf("revoking missing access signed out %v", signOuts)
+	}
+}
+
+func TestMembershipLookupReadsEveryPage(t *testing.T) {
+	c, server := newTestConnector(t, nil)
+	server.PageSize = 1
+	ctx := context.Background()
+
+	for _, tc := range []struct {
+		resourceType *v2.ResourceType
+		resourceID   string
+		slug         string
+		userID       int64
+		lookup       string
+		isMember     func() bool
+	}{
+		{resourceTypeGroup, "10", memberEntitlement, 3, "GET /api/v2/users/3/group_memberships.json", func() bool { return hasGroupMembership(server, 3, 10) }},
+		{resourceTypeOrg, "300", orgRoleMember, 4, "GET /api/v2/users/4/organization_memberships.json", func() bool { return hasOrgMembership(server, 4, 300) }},
+	} {
+		syncer := findSyncer(t, c, tc.resourceType)
+		provisioner, ok := syncer.(connectorbuilder.ResourceProvisionerV2)
+		if !ok {
+			t.Fatalf("%s syncer is not a provisioner", tc.resourceType.Id)
+		}
+		resource := findResource(t, listAll(t, syncer), tc.resourceID)
+		entitlement := findEntitlement(t, entitlementsAll(t, syncer, resource), tc.slug)
+		principal := teamMemberResource(t, tc.userID)
+
+		// The new membership follows the user's existing one, so a page of one only finds it on the second page.
+		_, _, err := provisioner.Grant(ctx, principal, entitlement)
+		if err != nil {
+			t.Fatalf("granting %s %s: %v", tc.resourceType.Id, tc.resourceID, err)
+		}
+		lookups := server.Requests(tc.lookup)
+
+		_, annos, err := provisioner.Grant(ctx, principal, entitlement)
+		if err != nil {
+			t.Errorf("granting %s %s again: %v", tc.resourceType.Id, tc.resourceID, err)
+		} else if !annos.Contains(&v2.GrantAlreadyExists{}) {
+			t.Errorf("granting %s %s again is not annotated as already existing", tc.resourceType.Id, tc.resourceID)
+		}
+
+		annos, err = provisioner.Revoke(ctx, &v2.Grant{Entitlement: entitlement, Principal: principal})
+		if err != nil {
+			t.Fatalf("revoking %s %s: %v", tc.resourceType.Id, tc.resourceID, err)
+		}
+		if annos.Contains(&v2.GrantAlreadyRevoked{}) || tc.isMember() {
+			t.Errorf("membership of %s %s on the second page was not revoked", tc.resourceType.Id, tc.resourceID)
+		}
+		if got := server.Requests(tc.lookup) - lookups; got < 2 {
+			t.Errorf("%s was requested %d times, want every page", tc.lookup, got)
+		}
+	}
+}
+
+func TestGrantReturnsGrantsWithMembershipIDs(t *testing.T) {
+	c, server := newTestConnector(t, nil)
+	ctx := context.Background()
+
+	for _, tc := range []struct {
+		resourceType *v2.ResourceType
+		resourceID   string
+		slug         string
+		userID       int64
+		lookup       string
+		isMember     func() bool
+	}{
+		{resourceTypeGroup, "11", memberEntitlement, 2, "GET /api/v2/group_memberships.json", func() bool { return hasGroupMembership(server, 2, 11) }},
+		{resourceTypeOrg, "300", orgRoleMember, 4, "GET /api/v2/organization_memberships.json", func() bool { return hasOrgMembership(server, 4, 300) }},
+	} {
+		syncer := findSyncer(t, c, tc.resourceType)
+		provisioner, ok := syncer.(connectorbuilder.ResourceProvisionerV2)
+		if !ok {
+			t.Fatalf("%s syncer is not a provisioner", tc.resourceType.Id)
+		}
+		resource := findResource(t, listAll(t, syncer), tc.resourceID)
+		entitlement := findEntitlement(t, entitlementsAll(t, syncer, resource), tc.slug)
+
+		grants, _, err := provisioner.Grant(ctx, teamMemberResource(t, tc.userID), entitlement)
+		if err != nil {
+			t.Fatalf("granting %s %s: %v", tc.resourceType.Id, tc.resourceID, err)
+		}
+
+		var granted *v2.Grant
+		for _, g := range grants {
+			if g.Entitlement.Id == entitlement.Id && g.Principal.Id.Resource == strconv.FormatInt(tc.userID, 10) {
+				granted = g
+			}
+		}
+		if granted == nil {
+			t.Fatalf("granting %s %s returned no grant of %s", tc.resourceType.Id, tc.resourceID, entitlement.Id)
+		}
+		if _, ok := grantMembershipID(granted); !ok {
+			t.Fatalf("grant of %s %s does not record its membership ID", tc.resourceType.Id, tc.resourceID)
+		}
+
+		// Revoking the returned grant deletes the membership by its ID, without looking it up.
+		lookups := server.Requests(tc.lookup)
+		_, err = provisioner.Revoke(ctx, granted)
+		if err != nil {
+			t.Fatalf("revoking %s %s: %v", tc.resourceType.Id, tc.resourceID, err)
+		}
+		if got := server.Requests(tc.lookup) - lookups; got != 0 {
+			t.Errorf("revoking %s %s looked up the membership %d times", tc.resourceType.Id, tc.resourceID, got)
+		}
+		if tc.isMember() {
+			t.Errorf("user %d is still a member of %s %s after revoke", tc.userID, tc.resourceType.Id, tc.resourceID)
+		}
+	}
+
+	roles := findSyncer(t, c, resourceTypeRole)
+	provisioner, ok := roles.(connectorbuilder.ResourceProvisionerV2)
+	if !ok {
+		t.Fatalf("role syncer is not a provisioner")
+	}
+	teamLead := findResource(t, listAll(t, roles), "501")
+	grants, _, err := provisioner.Grant(ctx, teamMemberResource(t, 3), findEntitlement(t, entitlementsAll(t, roles, teamLead), "agent"))
+	if err != nil {
+		t.Fatalf("granting role: %v", err)
+	}
+	if len(grants) != 2 || grants[0].Entitlement.Resource.Id.Resource != "501" || grants[0].Principal.Id.Resource != "3" {
+		t.Errorf("granting role returned %v, want the grants of role 501 to user 3", grants)
+	}
+}
+
+func TestRevokeWithStaleMembershipID(t *testing.T) {
+	for _, batch := range []bool{false, true} {
+		c, server := newTestConnector(t, func(cfg *Config) {
+			cfg.BatchProvisioning = batch
+		})
+		ctx := context.Background()
+
+		for _, tc := range []struct {
+			resourceType *v2.ResourceType
+			resourceID   string
+			slug         string
+			userID       int64
+			isMember     func() bool
+		}{
+			{resourceTypeGroup, "11", memberEntitlement, 2, func() bool { return hasGroupMembership(server, 2, 11) }},
+			{resourceTypeOrg, "300", orgRoleMember, 4, func() bool { return hasOrgMembership(server, 4, 300) }},
+		} {
+			syncer := findSyncer(t, c, tc.resourceType)
+			provisioner, ok := syncer.(connectorbuilder.ResourceProvisionerV2)
+			if !ok {
+				t.Fatalf("%s syncer is not a provisioner", tc.resourceType.Id)
+			}
+			resource := findResource(t, listAll(t, syncer), tc.resourceID)
+			entitlement := findEntitlement(t, entitlementsAll(t, syncer, resource), tc.slug)
+			user := teamMemberResource(t, tc.userID)
+
+			grant := func() *v2.Grant {
+				grants, _, err := provisioner.Grant(ctx, user, entitlement)
+				if err != nil {
+					t.Fatalf("granting %s %s: %v", tc.resourceType.Id, tc.resourceID, err)
+				}
+				for _, g := range grants {
+					if g.Entitlement.Id == entitlement.Id {
+						return g
+					}
+				}
+				t.Fatalf("granting %s %s returned no grant of %s", tc.resourceType.Id, tc.resourceID, entitlement.Id)
+				return nil
+			}
+
+			// The user is removed and added again, so the first grant records a membership that no longer exists.
+			stale := grant()
+			if _, err := provisioner.Revoke(ctx, stale); err != nil {
+				t.Fatalf("revoking %s %s: %v", tc.resourceType.Id, tc.resourceID, err)
+			}
+			current := grant()
+			staleID, _ := grantMembershipID(stale)
+			if currentID, _ := grantMembershipID(current); currentID == staleID {
+				t.Fatalf("granting %s %s again reused membership %d", tc.resourceType.Id, tc.resourceID, staleID)
+			}
+
+			annos, err := provisioner.Revoke(ctx, stale)
+			if err != nil {
+				t.Fatalf("revoking %s %s by a stale membership ID (batch %t): %v", tc.resourceType.Id, tc.resourceID, batch, err)
+			}
+			if annos.Contains(&v2.GrantAlreadyRevoked{}) || tc.isMember() {
+				t.Errorf("revoking %s %s by a stale membership ID (batch %t) left user %d a member", tc.resourceType.Id, tc.resourceID, batch, tc.userID)
+			}
+		}
+	}
+}
+
+func TestRoleGrantAndRevokeRestoresPreviousRole(t *testing.T) {
+	c, server := newTestConnector(t, func(cfg *Config) {
+		cfg.SignOutOnRoleRestore = true
+	})
+	ctx := context.Background()
+
+	syncer := findSyncer(t, c, resourceTypeRole)
+	provisioner, ok := syncer.(connectorbuilder.ResourceProvisionerV2)
+	if !ok {
+		t.Fatalf("role syncer is not a provisioner")
+	}
+
+	teamLead := findResource(t, listAll(t, syncer), "501")
+	entitlement := findEntitlement(t, entitlementsAll(t, syncer, teamLead), "agent")
+	principal := teamMemberResource(t, 2)
+
+	_, _, err := provisioner.Grant(ctx, principal, entitlement)
+	if err != nil {
+		t.Fatalf("granting: %v", err)
+	}
+	if user := findUser(t, server, 2); user.CustomRoleID != 501 {
+		t.Fatalf("custom role of user 2 is %d after grant, want 501", user.CustomRoleID)
+	}
+
+	_, err = provisioner.Revoke(ctx, &v2.Grant{Entitlement: entitlement, Principal: principal})
+	if err != nil {
+		t.Fatalf("revoking: %v", err)
+	}
+	user := findUser(t, server, 2)
+	if user.CustomRoleID != 500 || user.Role != "agent" {
+		t.Errorf("user 2 is %s with custom role %d after revoke, want agent with custom role 500", user.Role, user.CustomRoleID)
+	}
+	if len(user.Tags) != 0 {
+		t.Errorf("user 2 has tags %v after revoke, want none", user.Tags)
+	}
+	if signOuts := server.SignOuts(); len(signOuts) != 1 || signOuts[0] != 2 {
+		t.Errorf("got sign outs %v, want [2]", signOuts)
+	}
+}
+
+func TestRoleRevokeClearsCustomRole(t *testing.T) {
+	c, server := newTestConnector(t, nil)
+	ctx := context.Background()
+
+	syncer := findSyncer(t, c, resourceTypeRole)
+	provisioner, ok := syncer.(connectorbuilder.ResourceProvisionerV2)
+	if !ok {
+		t.Fatalf("role syncer is not a provisioner")
+	}
+
+	teamLead := findResource(t, listAll(t, syncer), "501")
+	entitlement := findEntitlement(t, entitlementsAll(t, syncer, teamLead), "agent")
+	principal := teamMemberResource(t, 3)
+
+	_, _, err := provisioner.Grant(ctx, principal, entitlement)
+	if err != nil {
+		t.Fatalf("granting: %v", err)
+	}
+	want := []string{"access-billing", "access-reports", "baton_previous_role_agent_0"}
+	if user := findUser(t, server, 3); user.CustomRoleID != 501 || !slices.Equal(user.Tags, want) {
+		t.Fatalf("user 3 has custom role %d and tags %v after grant, want 501 and %v", user.CustomRoleID, user.Tags, want)
+	}
+
+	_, err = provisioner.Revoke(ctx, &v2.Grant{Entitlement: entitlement, Principal: principal})
+	if err != nil {
+		t.Fatalf("revoking: %v", err)
+	}
+	user := findUser(t, server, 3)
+	if user.CustomRoleID != 0 || user.Role != "agent" {
+		t.Errorf("user 3 is %s with custom role %d after revoke, want agent without a custom role", user.Role, user.CustomRoleID)
+	}
+	if want := []string{"access-billing", "access-reports"}; !slices.Equal(user.Tags, want) {
+		t.Errorf("user 3 has tags %v after revoke, want %v", user.Tags, want)
+	}
+}
+
+func TestTeamMemberSessions(t *testing.T) {
+	for _, syncSessions := range []bool{false, true} {
+		c, server := newTestConnector(t, func(cfg *Config) {
+			cfg.SyncSessions = syncSessions
+		})
+
+		listAll(t, findSyncer(t, c, resourceTypeTeam))
+
+		got := server.Requests("GET /api/v2/users/3/sessions.json")
+		if syncSessions && got != 1 {
+			t.Errorf("sessions of user 3 were listed %d times, want once", got)
+		}
+		if !syncSessions && got != 0 {
+			t.Errorf("sessions were listed %d times without --sync-sessions", got)
+		}
+	}
+}
+
+func TestUserDirectoryIsShared(t *testing.T) {
+	c, server := newTestConnector(t, nil)
+	ctx := context.Background()
+
+	// Team members are listed once, and the roles and groups find them in the directory.
+	listAll(t, findSyncer(t, c, resourceTypeTeam))
+
+	roleSyncer := findSyncer(t, c, resourceTypeRole)
+	roles := listAll(t, roleSyncer)
+	for _, role := range roles {
+		entitlementsAll(t, roleSyncer, role)
+		grantsAll(t, roleSyncer, role)
+	}
+
+	groupSyncer := findSyncer(t, c, resourceTypeGroup)
+	for _, group := range listAll(t, groupSyncer) {
+		grantsAll(t, groupSyncer, group)
+	}
+
+	if got := server.Requests("GET /api/v2/users.json"); got != 1 {
+		t.Errorf("users were listed %d times, want 1", got)
+	}
+	if got := server.Requests("GET /api/v2/users/show_many.json"); got != 0 {
+		t.Errorf("users were fetched by ID %d times, want 0", got)
+	}
+
+	// Granting a role invalidates the team member in the directory, so the next grants see the change.
+	provisioner, ok := roleSyncer.(connectorbuilder.ResourceProvisionerV2)
+	if !ok {
+		t.Fatalf("role syncer is not a provisioner")
+	}
+	teamLead := findResource(t, roles, "501")
+	_, _, err := provisioner.Grant(ctx, teamMemberResource(t, 3), findEntitlement(t, entitlementsAll(t, roleSyncer, teamLead), "agent"))
+	if err != nil {
+		t.Fatalf("granting: %v", err)
+	}
+
+	if got := grantedTeamMembers(grantsAll(t, roleSyncer, teamLead), "agent"); got["3"] == 0 {
+		t.Errorf("user 3 has no grant of role 501 after grant, got %v", got)
+	}
+	// Only the invalidated team member is fetched again, by ID.
+	if got := server.Requests("GET /api/v2/users.json"); got != 1 {
+		t.Errorf("users were listed %d times, want 1", got)
+	}
+	if got := server.Requests("GET /api/v2/users/show_many.json"); got == 0 {
+		t.Errorf("the invalidated team member was not fetched again")
+	}
+}
+
+func TestGrantAndRevokeByExternalID(t *testing.T) {
+	c, server := newTestConnector(t, nil)
+	ctx := context.Background()
+
+	syncer := findSyncer(t, c, resourceTypeGroup)
+	provisioner, ok := syncer.(connectorbuilder.ResourceProvisionerV2)
+	if !ok {
+		t.Fatalf("group syncer is not a provisioner")
+	}
+
+	billing := findResource(t, listAll(t, syncer), "11")
+	entitlement := findEntitlement(t, entitlementsAll(t, syncer, billing), memberEntitlement)
+	principal := &v2.Resource{
+		Id: &v2.ResourceId{ResourceType: resourceTypeTeam.Id, Resource: "idp-alice"},
+	}
+
+	_, _, err := provisioner.Grant(ctx, principal, entitlement)
+	if err != nil {
+		t.Fatalf("granting: %v", err)
+	}
+	if !hasGroupMembership(server, 1, 11) {
+		t.Fatalf("user 1 is not a member of group 11 after grant by external ID")
+	}
+
+	_, err = provisioner.Revoke(ctx, &v2.Grant{Entitlement: entitlement, Principal: principal})
+	if err != nil {
+		t.Fatalf("revoking group membership: %v", err)
+	}
+	if hasGroupMembership(server, 1, 11) {
+		t.Errorf("user 1 is still a member of group 11 after revoke by external ID")
+	}
+
+	orgs := findSyncer(t, c, resourceTypeOrg)
+	orgProvisioner, ok := orgs.(connectorbuilder.ResourceProvisionerV2)
+	if !ok {
+		t.Fatalf("org syncer is not a provisioner")
+	}
+	acme := findResource(t, listAll(t, orgs), "100")
+	_, err = orgProvisioner.Revoke(ctx, &v2.Grant{Entitlement: findEntitlement(t, entitlementsAll(t, orgs, acme), orgRoleMember), Principal: principal})
+	if err != nil {
+		t.Fatalf("revoking org membership: %v", err)
+	}
+	if hasOrgMembership(server, 1, 100) {
+		t.Errorf("user 1 is still a member of org 100 after revoke by external ID")
+	}
+
+	roles := findSyncer(t, c, resourceTypeRole)
+	roleProvisioner, ok := roles.(connectorbuilder.ResourceProvisionerV2)
+	if !ok {
+		t.Fatalf("role syncer is not a provisioner")
+	}
+	teamLead := findResource(t, listAll(t, roles), "501")
+	roleEntitlement := findEntitlement(t, entitlementsAll(t, roles, teamLead), "admin")
+	_, _, err = roleProvisioner.Grant(ctx, principal, roleEntitlement)
+	if err != nil {
+		t.Fatalf("granting role: %v", err)
+	}
+	_, err = roleProvisioner.Revoke(ctx, &v2.Grant{Entitlement: roleEntitlement, Principal: principal})
+	if err != nil {
+		t.Fatalf("revoking role: %v", err)
+	}
+	if user := findUser(t, server, 1); user.CustomRoleID != 0 {
+		t.Errorf("user 1 has custom role %d after revoke by external ID, want none", user.CustomRoleID)
+	}
+}
+
+func TestTags(t *testing.T) {
+	c, server := newTestConnector(t, func(cfg *Config) {
+		cfg.TagPrefixes = []string{"access-"}
+		cfg.TagIncludeOrgs = true
+		cfg.Orgs = []string{"Globex"}
+	})
+	server.PageSize = 1
+	ctx := context.Background()
+
+	syncer := findSyncer(t, c, resourceTypeTag)
+	provisioner, ok := syncer.(connectorbuilder.ResourceProvisioner)
+	if !ok {
+		t.Fatalf("tag syncer is not a provisioner")
+	}
+
+	// Tags carried on several pages of search results are listed again, and only end-user 4 carries access-portal.
+	tags := listAll(t, syncer)
+	var names []string
+	for _, tag := range tags {
+		names = append(names, tag.Id.Resource)
+	}
+	slices.Sort(names)
+	if names = slices.Compact(names); !slices.Equal(names, []string{"access-billing", "access-reports"}) {
+		t.Fatalf("got tags %v, want [access-billing access-reports] from team members and synced orgs", names)
+	}
+
+	billing := findResource(t, tags, "access-billing")
+	entitlement := findEntitlement(t, entitlementsAll(t, syncer, billing), tagMemberEntitlement)
+
+	// End-user 4 and org 300, which the orgs filter leaves out, carry the tag without being granted it.
+	var principals []string
+	for _, g := range grantsAll(t, syncer, billing) {
+		principals = append(principals, g.Principal.Id.ResourceType+":"+g.Principal.Id.Resource)
+	}
+	if want := []string{"team_member:1", "team_member:3", "org:200"}; !slices.Equal(principals, want) {
+		t.Errorf("got access-billing grants to %v, want %v", principals, want)
+	}
+
+	_, err := provisioner.Grant(ctx, teamMemberResource(t, 2), entitlement)
+	if err != nil {
+		t.Fatalf("granting: %v", err)
+	}
+	if user := findUser(t, server, 2); !slices.Equal(user.Tags, []string{"access-billing"}) {
+		t.Errorf("user 2 has tags %v after grant, want [access-billing]", user.Tags)
+	}
+
+	principal := &v2.Resource{
+		Id: &v2.ResourceId{ResourceType: resourceTypeTeam.Id, Resource: "idp-alice"},
+	}
+	_, err = provisioner.Revoke(ctx, &v2.Grant{Entitlement: entitlement, Principal: principal})
+	if err != nil {
+		t.Fatalf("revoking by external ID: %v", err)
+	}
+	if user := findUser(t, server, 1); len(user.Tags) != 0 {
+		t.Errorf("user 1 has tags %v after revoke, want none", user.Tags)
+	}
+}
+
+func TestAPITokenRotation(t *testing.T) {
+	c, server := newTestConnector(t, nil)
+	ctx := context.Background()
+
+	syncer := findSyncer(t, c, resourceTypeAPIToken)
+	manager, ok := syncer.(connectorbuilder.CredentialManager)
+	if !ok {
+		t.Fatalf("api token syncer is not a credential manager")
+	}
+
+	tokens := listAll(t, syncer)
+	if len(tokens) != 2 {
+		t.Fatalf("listed %d api tokens, want 2", len(tokens))
+	}
+
+	// The token the connector authenticates with is never rotated.
+	_, _, err := manager.Rotate(ctx, findResource(t, tokens, "7").Id, nil)
+	if status.Code(err) != codes.FailedPrecondition {
+		t.Errorf("rotating the connector's own api token returned %v, want a failed precondition", err)
+	}
+
+	rotated, _, err := manager.Rotate(ctx, findResource(t, tokens, "8").Id, nil)
+	if err != nil {
+		t.Fatalf("rotating: %v", err)
+	}
+	remaining := server.APITokens()
+	if len(remaining) != 2 || remaining[1].ID == 8 || len(rotated) != 1 || string(rotated[0].Bytes) != remaining[1].Token {
+		t.Fatalf("rotating returned %v and left tokens %v, want the value of a replacement of token 8", rotated, remaining)
+	}
+
+	// A replacement is deleted again when the old token cannot be deleted.
+	replacement := remaining[1].ID
+	server.Fail("DELETE /api/v2/api_tokens/"+strconv.FormatInt(replacement, 10)+".json", http.StatusInternalServerError)
+	_, _, err = manager.Rotate(ctx, &v2.ResourceId{ResourceType: resourceTypeAPIToken.Id, Resource: strconv.FormatInt(replacement, 10)}, nil)
+	if err == nil {
+		t.Errorf("rotating succeeded although the old token was not deleted")
+	}
+	if got := server.APITokens(); len(got) != 2 {
+		t.Errorf("failed rotation left tokens %v, want the tokens as they were", got)
+	}
+
+	// When the replacement cannot be deleted either, the rotation fails naming both tokens.
+	next := server.APITokens()
+	orphan := strconv.FormatInt(replacement+2, 10)
+	server.Fail("DELETE /api/v2/api_tokens/"+orphan+".json", http.StatusInternalServerError)
+	_, _, err = manager.Rotate(ctx, &v2.ResourceId{ResourceType: resourceTypeAPIToken.Id, Resource: strconv.FormatInt(replacement, 10)}, nil)
+	if status.Code(err) != codes.Internal || !strings.Contains(err.Error(), orphan) {
+		t.Errorf("rotating with a failed rollback returned %v, want an internal error naming token %s", err, orphan)
+	}
+	if got := server.APITokens(); len(got) != len(next)+1 {
+		t.Errorf("rotating with a failed rollback left tokens %v, want the old token and its replacement", got)
+	}
+
+	details, _, err := manager.RotateCapabilityDetails(ctx)
+	if err != nil {
+		t.Fatalf("rotate capability details: %v", err)
+	}
+	if details.PreferredCredentialOption != v2.CapabilityDetailCredentialOption_CAPABILITY_DETAIL_CREDENTIAL_OPTION_NO_PASSWORD {
+		t.Errorf("preferred credential option is %s, want no password", details.PreferredCredentialOption)
+	}
+}
+
+func TestAppInstallationGrantsExpand(t *testing.T) {
+	c, _ := newTestConnector(t, nil)
+
+	syncer := findSyncer(t, c, resourceTypeAppInstallation)
+	grants := grantsAll(t, syncer, findResource(t, listAll(t, syncer), "50"))
+
+	expanded := make(map[string][]string)
+	for _, g := range grants {
+		expandable := &v2.GrantExpandable{}
+		annos := annotations.Annotations(g.Annotations)
+		if ok, err := annos.Pick(expandable); err != nil || !ok {
+			t.Errorf("grant to %s %s is not expandable", g.Principal.Id.ResourceType, g.Principal.Id.Resource)
+			continue
+		}
+		expanded[g.Principal.Id.ResourceType+":"+g.Principal.Id.Resource] = expandable.EntitlementIds
+	}
+
+	want := map[string][]string{
+		"role:500": {"role:500:admin", "role:500:agent"},
+		"group:10": {"group:10:member"},
+	}
+	for principal, ids := range want {
+		if !slices.Equal(expanded[principal], ids) {
+			t.Errorf("grant to %s expands to %v, want %v", principal, expanded[principal], ids)
+		}
+	}
+}
+
+func TestWebhooksAndTargets(t *testing.T) {
+	c, _ := newTestConnector(t, nil)
+
+	webhooks := listAll(t, findSyncer(t, c, resourceTypeWebhook))
+	targets := listAll(t, findSyncer(t, c, resourceTypeTarget))
+
+	for _, tc := range []struct {
+		resource      *v2.Resource
+		authType      string
+		signingSecret bool
+		endpointHost  string
+	}{
+		{findResource(t, webhooks, "01HWEBHOOKSIGNED"), "bearer_token", true, "hooks.example.com"},
+		{findResource(t, webhooks, "01HWEBHOOKPLAIN"), "none", false, "plain.example.com"},
+		{findResource(t, targets, "60"), "basic_auth", false, "legacy.example.com"},
+	} {
+		trait, err := rs.GetAppTrait(tc.resource)
+		if err != nil {
+			t.Fatalf("%s has no app trait: %v", tc.resource.Id.Resource, err)
+		}
+		profile := trait.GetProfile().AsMap()
+
+		if profile["auth_type"] != tc.authType {
+			t.Errorf("%s has auth type %v, want %s", tc.resource.Id.Resource, profile["auth_type"], tc.authType)
+		}
+		if present, ok := profile["signing_secret_present"]; ok && present != tc.signingSecret {
+			t.Errorf("%s has signing secret present %v, want %v", tc.resource.Id.Resource, present, tc.signingSecret)
+		}
+		if profile["endpoint_host"] != tc.endpointHost {
+			t.Errorf("%s has endpoint host %v, want %s", tc.resource.Id.Resource, profile["endpoint_host"], tc.endpointHost)
+		}
+
+		// Credentials are never copied into resources.
+		for key, value := range profile {
+			if str, ok := value.(string); ok && strings.Contains(str, "secret") {
+				t.Errorf("%s profile field %s leaks a credential: %q", tc.resource.Id.Resource, key, str)
+			}
+		}
+	}
+}
+
+func TestSSOStatus(t *testing.T) {
+	ssoEnabled := func(c *Connector) bool {
+		t.Helper()
+
+		team := findResource(t, listAll(t, findSyncer(t, c, resourceTypeTeam)), "3")
+		trait, err := rs.GetUserTrait(team)
+		if err != nil {
+			t.Fatalf("team member has no user trait: %v", err)
+		}
+
+		return trait.GetSsoStatus().GetSsoEnabled()
+	}
+
+	// Without security settings, the configured status is used.
+	c, server := newTestConnector(t, func(cfg *Config) {
+		cfg.SSOEnforced = true
+	})
+	if !ssoEnabled(c) {
+		t.Errorf("SSO is disabled although it is configured and the account settings do not say")
+	}
+
+	// The security settings of the account take precedence over the configured status.
+	server.SetAgentSSO(false)
+	if ssoEnabled(c) {
+		t.Errorf("SSO is enabled although the account settings disable it")
+	}
+
+	c, server = newTestConnector(t, nil)
+	server.SetAgentSSO(true)
+	if !ssoEnabled(c) {
+		t.Errorf("SSO is disabled although the account settings enable it")
+	}
+}
+
+func TestAPIOnlyAccounts(t *testing.T) {
+	traits, err := newUserTraitConfig(false, nil, nil, true, []string{`(?i)(^|[^a-z])(api|integration|bot|no-?reply)([^a-z]|$)`})
+	if err != nil {
+		t.Fatalf("newUserTraitConfig: %v", err)
+	}
+
+	tests := []struct {
+		user        zendesk.User
+		accountType v2.UserTrait_AccountType
+		profile     map[string]interface{}
+	}{
+		// Invited team members that have not signed in yet are not service accounts.
+		{zendesk.User{Name: "Jane Doe", Email: "jane@example.com"}, v2.UserTrait_ACCOUNT_TYPE_HUMAN, nil},
+		{zendesk.User{Name: "CRM sync", Email: "api-crm@example.com"}, v2.UserTrait_ACCOUNT_TYPE_SERVICE, map[string]interface{}{"service_account_reason": "api_only"}},
+		{zendesk.User{Name: "CRM sync", Email: "api-crm@example.com", LastLoginAt: time.Now()}, v2.UserTrait_ACCOUNT_TYPE_HUMAN, nil},
+	}
+
+	for _, tt := range tests {
+		opts, profile := traits.accountTypeTraitOptions(&tt.user)
+		trait := &v2.UserTrait{}
+		for _, opt := range opts {
+			if err := opt(trait); err != nil {
+				t.Fatalf("applying trait option: %v", err)
+			}
+		}
+
+		if trait.GetAccountType() != tt.accountType {
+			t.Errorf("%s: account type is %s, want %s", tt.user.Email, trait.GetAccountType(), tt.accountType)
+		}
+		if !reflect.DeepEqual(profile, tt.profile) {
+			t.Errorf("%s: profile is %v, want %v", tt.user.Email, profile, tt.profile)
+		}
+	}
+}
+
+func findEntitlement(t *testing.T, entitlements []*v2.Entitlement, slug string) *v2.Entitlement {
+	t.Helper()
+
+	for _, e := range entitlements {
+		if e.Slug == slug {
+			return e
+		}
+	}
+
+	t.Fatalf("entitlement %s not found", slug)
+	return nil
+}
+
+func teamMemberResource(t *testing.T, userID int64) *v2.Resource {
+	t.Helper()
+
+	resource, err := rs.NewResource(strconv.FormatInt(userID, 10), resourceTypeTeam, userID)
+	if err != nil {
+		t.Fatalf("creating team member resource: %v", err)
+	}
+
+	return resource
+}
+
+func findUser(t *testing.T, server *zendesktest.Server, userID int64) zendesk.User {
+	t.Helper()
+
+	for _, user := range server.Users() {
+		if user.ID == userID {
+			return user
+		}
+	}
+
+	t.Fatalf("user %d not found", userID)
+	return zendesk.User{}
+}
+
+func hasGroupMembership(server *zendesktest.Server, userID int64, groupID int64) bool {
+	for _, membership := range server.GroupMemberships() {
+		if membership.UserID == userID && membership.GroupID == groupID {
+			return true
+		}
+	}
+
+	return false
+}
+
+func hasOrgMembership(server *zendesktest.Server, userID int64, orgID int64) bool {
+	for _, membership := range server.OrganizationMemberships() {
+		if membership.UserID == userID && membership.OrganizationID == orgID {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,163 @@
+package connector
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/conductorone/baton-zendesk/pkg/client"
+)
+
+// customFieldConfig holds the custom user and organization field keys copied into profiles,
+// and caches the field types so that values are typed according to their definitions.
+type customFieldConfig struct {
+	userFieldKeys []string
+	orgFieldKeys  []string
+
+	mu             sync.Mutex
+	userFieldTypes map[string]string
+	orgFieldTypes  map[string]string
+}
+
+func newCustomFieldConfig(userFieldKeys []string, orgFieldKeys []string) *customFieldConfig {
+	return &customFieldConfig{
+		userFieldKeys: userFieldKeys,
+		orgFieldKeys:  orgFieldKeys,
+	}
+}
+
+// userFields returns the configured custom user field values, typed according to their definitions.
+func (c *customFieldConfig) userFields(ctx context.Context, zc *client.ZendeskClient, values map[string]interface{}) (map[string]interface{}, error) {
+	if len(c.userFieldKeys) == 0 {
+		return nil, nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.userFieldTypes == nil {
+		fields, err := zc.ListUserFields(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("zendesk-connector: failed to list user fields: %w", err)
+		}
+
+		c.userFieldTypes = make(map[string]string, len(fields))
+		for _, field := range fields {
+			c.userFieldTypes[field.Key] = field.Type
+		}
+	}
+
+	return typedFieldValues(c.userFieldKeys, c.userFieldTypes, values), nil
+}
+
+// orgFields returns the configured custom organization field values, typed according to their definitions.
+func (c *customFieldConfig) orgFields(ctx context.Context, zc *client.ZendeskClient, values map[string]interface{}) (map[string]interface{}, error) {
+	if len(c.orgFieldKeys) == 0 {
+		return nil, nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.orgFieldTypes == nil {
+		fields, err := zc.ListOrganizationFields(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("zendesk-connector: failed to list organization fields: %w", err)
+		}
+
+		c.orgFieldTypes = make(map[string]string, len(fields))
+		for _, field := range fields {
+			c.orgFieldTypes[field.Key] = field.Type
+		}
+	}
+
+	return typedFieldValues(c.orgFieldKeys, c.orgFieldTypes, values), nil
+}
+
+// fieldsDescription renders custom field values as a description, since orgs have no trait to carry a profile.
+func fieldsDescription(fields map[string]interface{}) string {
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+
+	parts := make([]string, 0, len(keys))
+	for _, key := range keys {
+		parts = append(parts, fmt.Sprintf("%s: %v", key, fields[key]))
+	}
+
+	return strings.Join(parts, "; ")
+}
+
+// typedFieldValues picks the given keys out of the field values. Keys without a value or a definition are skipped.
+func typedFieldValues(keys []string, types map[string]string, values map[string]interface{}) map[string]interface{} {
+	rv := make(map[string]interface{}, len(keys))
+	for _, key := range keys {
+		fieldType, ok := types[key]
+		if !ok {
+			continue
+		}
+
+		value, ok := typedFieldValue(fieldType, values[key])
+		if !ok {
+			continue
+		}
+		rv[key] = value
+	}
+
+	return rv
+}
+
+// typedFieldValue converts a custom field value decoded from JSON into the type of its field definition.
+func typedFieldValue(fieldType string, value interface{}) (interface{}, bool) {
+	if value == nil {
+		return nil, false
+	}
+
+	switch fieldType {
+	case "integer", "lookup":
+		switch v := value.(type) {
+		case float64:
+			return int64(v), true
+		case string:
+			i, err := strconv.ParseInt(v, 10, 64)
+			return i, err == nil
+		}
+	case "decimal":
+		switch v := value.(type) {
+		case float64:
+			return v, true
+		case string:
+			f, err := strconv.ParseFloat(v, 64)
+			return f, err == nil
+		}
+	case "checkbox":
+		switch v := value.(type) {
+		case bool:
+			return v, true
+		case string:
+			b, err := strconv.ParseBool(v)
+			return b, err == nil
+		}
+	case "multiselect":
+		if v, ok := value.([]interface{}); ok {
+			options := make([]interface{}, 0, len(v))
+			for _, option := range v {
+				options = append(options, fmt.Sprint(option))
+			}
+			return options, true
+		}
+	default:
+		// Text, textarea, regexp, dropdown and date fields hold strings.
+		if v, ok := value.(string); ok {
+			return v, true
+		}
+		return fmt.Sprint(value), true
+	}
+
+	return nil, false
+}
@@ -2,7 +2,9 @@ package connector
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"regexp"
 	"strconv"
 
 	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
@@ -24,6 +26,81 @@ const (
 type groupResourceType struct {
 	resourceType *v2.ResourceType
 	client       *client.ZendeskClient
+	users        *userDirectory
+	members      *teamMemberFilter
+	filter       *groupFilter
+	prefetch     *prefetcher
+	sweep        *membershipSweep
+	batch        *membershipBatcher
+}
+
+// groupGrantsPage is a page of the memberships of a group, along with the first page of team members
+// used to build the grants without fetching every member.
+type groupGrantsPage struct {
+	users         map[int64]zendesk.User
+	memberships   []zendesk.GroupMembership
+	nextPageToken string
+}
+
+func groupPrefetchKey(groupID int64) string {
+	return fmt.Sprintf("group:%d", groupID)
+}
+
+// groupFilter limits the groups governed by the connector to those listed by name or ID,
+// or whose name matches a pattern. Every group is governed when neither is configured.
+type groupFilter struct {
+	groups    map[string]struct{}
+	nameRegex *regexp.Regexp
+}
+
+func newGroupFilter(groups []string, nameRegex string) (*groupFilter, error) {
+	f := &groupFilter{
+		groups: make(map[string]struct{}, len(groups)),
+	}
+
+	for _, group := range groups {
+		f.groups[group] = struct{}{}
+	}
+
+	if nameRegex != "" {
+		re, err := regexp.Compile(nameRegex)
+		if err != nil {
+			return nil, fmt.Errorf("zendesk-connector: invalid group name pattern %q: %w", nameRegex, err)
+		}
+		f.nameRegex = re
+	}
+
+	return f, nil
+}
+
+func (f *groupFilter) includes(groupID int64, name string) bool {
+	if len(f.groups) == 0 && f.nameRegex == nil {
+		return true
+	}
+	if _, ok := f.groups[name]; ok {
+		return true
+	}
+	if _, ok := f.groups[strconv.FormatInt(groupID, 10)]; ok {
+		return true
+	}
+	if f.nameRegex != nil && f.nameRegex.MatchString(name) {
+		return true
+	}
+
+	return false
+}
+
+// checkGovernedGroup returns an error if the group is not governed by the connector, so that it is never changed.
+func (g *groupResourceType) checkGovernedGroup(ctx context.Context, groupID int64) error {
+	group, err := g.client.GetGroupDetails(ctx, groupID)
+	if err != nil {
+		return fmt.Errorf("zendesk-connector: failed to get group %d: %w", groupID, err)
+	}
+	if !g.filter.includes(group.ID, group.Name) {
+		return fmt.Errorf("zendesk-connector: group %s is not governed by this connector", group.Name)
+	}
+
+	return nil
 }
 
 var groupEntitlementAccessLevels = []string{
@@ -50,17 +127,37 @@ func (g *groupResourceType) List(ctx context.Context, parentId *v2.ResourceId, p
 		}
 	}
 
+	// A sync starts by listing the first page of groups, so memberships are swept or prefetched again for its grants.
+	if pageToken == 0 {
+		if g.sweep != nil {
+			g.sweep.invalidateGroups(ctx)
+		} else {
+			g.prefetch.reset()
+		}
+	}
+
 	groups, nextPageToken, err := g.client.ListGroups(ctx, pageToken)
 	if err != nil {
 		return nil, "", nil, err
 	}
 
 	for _, group := range groups {
+		if !g.filter.includes(group.ID, group.Name) {
+			continue
+		}
+
 		res, err := getGroupResource(group, resourceTypeGroup, parentId)
 		if err != nil {
 			return nil, "", nil, err
 		}
 
+		if g.sweep == nil {
+			groupID := group.ID
+			g.prefetch.queue(groupPrefetchKey(groupID), func(ctx context.Context) (interface{}, error) {
+				return g.fetchGrantsPage(ctx, groupID, 0)
+			})
+		}
+
 		ret = append(ret, res)
 	}
 
@@ -84,51 +181,141 @@ func (g *groupResourceType) Entitlements(_ context.Context, resource *v2.Resourc
 }
 
 func (g *groupResourceType) Grants(ctx context.Context, resource *v2.Resource, token *pagination.Token) ([]*v2.Grant, string, annotations.Annotations, error) {
-	var rv []*v2.Grant
+	var (
+		rv        []*v2.Grant
+		pageToken int
+	)
 	groupId, err := strconv.Atoi(resource.Id.Resource)
 	if err != nil {
 		return nil, "", nil, err
 	}
+	if !g.filter.includes(int64(groupId), resource.DisplayName) {
+		return nil, "", nil, nil
+	}
+	if token.Token != "" {
+		pageToken, err = strconv.Atoi(token.Token)
+		if err != nil {
+			return nil, "", nil, err
+		}
+	}
+
+	var page *groupGrantsPage
+	if g.sweep != nil {
+		page, err = g.sweptGrantsPage(ctx, int64(groupId), pageToken)
+		if err != nil {
+			return nil, "", nil, err
+		}
+	} else if pageToken == 0 {
+		if prefetched, ok := g.prefetch.take(ctx, groupPrefetchKey(int64(groupId))); ok {
+			page = prefetched.(*groupGrantsPage)
+		}
+	}
+	if page == nil {
+		page, err = g.fetchGrantsPage(ctx, int64(groupId), pageToken)
+		if err != nil {
+			return nil, "", nil, err
+		}
+	}
 
-	opts := zendesk.UserListOptions{
-		Roles: []string{
-			"admin",
-			"agent",
-		},
+	for _, group := range page.memberships {
+		// Only the first page of team members is prefetched, the others are fetched one by one.
+		// Memberships swept account-wide come with their users, unless they were missing from the sweep.
+		userAccountDetail, ok := page.users[group.UserID]
+		if !ok {
+			userAccountDetail, err = g.users.get(ctx, group.UserID)
+			if err != nil {
+				return nil, "", nil, fmt.Errorf("error fetching team member %d of group %s: %w", group.UserID, resource.Id.Resource, err)
+			}
+		}
+		// Members outside the configured team member roles have no team_member resource to grant to.
+		if !g.members.includes(&userAccountDetail) {
+			continue
+		}
+
+		grants, err := groupMembershipGrants(resource, userAccountDetail, group.ID)
+		if err != nil {
+			return nil, "", nil, fmt.Errorf("error creating team_member resource for group %s: %w", resource.Id.Resource, err)
+		}
+		rv = append(rv, grants...)
 	}
 
-	users, _, err := g.client.GetUsers(ctx, &opts)
+	return rv, page.nextPageToken, nil, nil
+}
+
+// groupMembershipGrants returns the grants of a team member's membership in a group, recording the ID of the
+// membership when it is known. Admins are granted the admin entitlement of their groups as well.
+func groupMembershipGrants(resource *v2.Resource, user zendesk.User, membershipID int64) ([]*v2.Grant, error) {
+	var rv []*v2.Grant
+
+	ur, err := getUserResource(user, resourceTypeTeam)
 	if err != nil {
-		return nil, "", nil, err
+		return nil, err
+	}
+
+	if user.Role == adminEntitlement {
+		adminsGrant := grant.NewGrant(resource, adminEntitlement, ur.Id, withMembershipID(membershipID))
+		teamAdminsGrant := grant.NewGrant(ur, adminEntitlement, resource.Id, withMembershipID(membershipID))
+		rv = append(rv, adminsGrant, teamAdminsGrant)
 	}
 
-	groupMemberships, nextPageToken, err := g.client.GetGroupMemberships(ctx, int64(groupId))
+	membershipGrant := grant.NewGrant(resource, memberEntitlement, ur.Id, withMembershipID(membershipID))
+	teamMembershipGrant := grant.NewGrant(ur, memberEntitlement, resource.Id, withMembershipID(membershipID))
+	rv = append(rv, membershipGrant, teamMembershipGrant)
+
+	return rv, nil
+}
+
+func (g *groupResourceType) fetchGrantsPage(ctx context.Context, groupID int64, pageToken int) (*groupGrantsPage, error) {
+	groupMemberships, nextPageToken, err := g.client.GetGroupMemberships(ctx, groupID, pageToken)
 	if err != nil {
-		return nil, "", nil, err
+		return nil, err
 	}
 
-	for _, group := range groupMemberships {
-		userAccountDetail := getUserByID(group.UserID, users)
-		ur, err := getUserResource(userAccountDetail, resourceTypeTeam)
-		if err != nil {
-			return nil, "", nil, fmt.Errorf("error creating team_member resource for group %s: %w", resource.Id.Resource, err)
-		}
+	userIDs := make([]int64, 0, len(groupMemberships))
+	for _, membership := range groupMemberships {
+		userIDs = append(userIDs, membership.UserID)
+	}
 
-		if userAccountDetail.Role == adminEntitlement {
-			adminsGrant := grant.NewGrant(resource, adminEntitlement, ur.Id)
-			teamAdminsGrant := grant.NewGrant(ur, adminEntitlement, resource.Id)
-			rv = append(rv, adminsGrant, teamAdminsGrant)
-		}
+	users, err := g.users.getMany(ctx, userIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	return &groupGrantsPage{
+		users:         users,
+		memberships:   groupMemberships,
+		nextPageToken: nextPageToken,
+	}, nil
+}
+
+// sweptGrantsPage returns a page of the memberships of a group from the account-wide membership index.
+func (g *groupResourceType) sweptGrantsPage(ctx context.Context, groupID int64, offset int) (*groupGrantsPage, error) {
+	idx, err := g.sweep.groupIndex(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	swept, users, nextPageToken, err := idx.page(groupID, offset)
+	idx.release(ctx)
+	if err != nil {
+		return nil, err
+	}
 
-		membershipGrant := grant.NewGrant(resource, memberEntitlement, ur.Id)
-		teamMembershipGrant := grant.NewGrant(ur, memberEntitlement, resource.Id)
-		rv = append(rv, membershipGrant, teamMembershipGrant)
+	memberships := make([]zendesk.GroupMembership, 0, len(swept))
+	for _, m := range swept {
+		memberships = append(memberships, zendesk.GroupMembership{ID: m.id, GroupID: groupID, UserID: m.userID})
 	}
 
-	return rv, nextPageToken, nil, nil
+	return &groupGrantsPage{
+		users:         users,
+		memberships:   memberships,
+		nextPageToken: nextPageToken,
+	}, nil
 }
 
-func (g *groupResourceType) Grant(ctx context.Context, principal *v2.Resource, entitlement *v2.Entitlement) (annotations.Annotations, error) {
+// Grant adds a team member to a group, and returns the grants of the membership along with its ID,
+// so that revoking them deletes the membership directly.
+func (g *groupResourceType) Grant(ctx context.Context, principal *v2.Resource, entitlement *v2.Entitlement) ([]*v2.Grant, annotations.Annotations, error) {
 	l := ctxzap.Extract(ctx)
 	if principal.Id.ResourceType != resourceTypeTeam.Id {
 		l.Warn(
@@ -136,48 +323,94 @@ func (g *groupResourceType) Grant(ctx context.Context, principal *v2.Resource, e
 			zap.String("principal_type", principal.Id.ResourceType),
 			zap.String("principal_id", principal.Id.Resource),
 		)
-		return nil, fmt.Errorf("zendesk-connector: only users can be granted team membership")
+		return nil, nil, fmt.Errorf("zendesk-connector: only users can be granted team membership")
 	}
 
-	userID, err := strconv.ParseInt(principal.Id.Resource, 10, 64)
+	userID, err := resolveUserID(ctx, g.client, principal)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
-	user, err := g.client.GetUser(ctx, userID)
+	user, err := g.users.get(ctx, userID)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	if user.Role == "end-user" {
 		l.Warn("user must be a team member",
 			zap.Int64("UserID", user.ID),
 			zap.String("user.Role", user.Role),
 		)
-		return nil, fmt.Errorf("user must be a team member")
+		return nil, nil, fmt.Errorf("user must be a team member")
 	}
 
 	groupID, err := strconv.ParseInt(entitlement.Resource.Id.Resource, 10, 64)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
+	}
+
+	err = g.checkGovernedGroup(ctx, groupID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if g.batch != nil {
+		membershipID, err := g.batch.addGroups.submit(ctx, groupID, userID, 0)
+		if errors.Is(err, errMembershipExists) {
+			return groupAlreadyGranted(ctx, entitlement.Resource, user, membershipID)
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("zendesk-connector: failed to add team member to a group: %w", err)
+		}
+
+		g.users.invalidate(userID)
+		if g.sweep != nil {
+			g.sweep.addGroupMembership(membership{id: membershipID, parentID: groupID, userID: userID})
+		}
+
+		l.Warn("Membership has been created.",
+			zap.Int64("ID", membershipID),
+			zap.Int64("UserID", userID),
+			zap.Int64("GroupID", groupID),
+		)
+
+		return groupGranted(entitlement.Resource, user, membershipID)
 	}
 
 	groupMembershipOptions := zendesk.GroupMembership{
 		UserID:  userID,
 		GroupID: groupID,
 	}
-	membership, err := g.client.CreateGroupMembership(ctx, groupMembershipOptions)
+	existingID, _, err := g.client.GetGroupMembershipByGroup(ctx, groupMembershipOptions)
+	if err == nil {
+		membershipID, err := strconv.ParseInt(existingID, 10, 64)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		return groupAlreadyGranted(ctx, entitlement.Resource, user, membershipID)
+	}
+	if !errors.Is(err, client.ErrMembershipNotFound) {
+		return nil, nil, err
+	}
+
+	created, err := g.client.CreateGroupMembership(ctx, groupMembershipOptions)
 	if err != nil {
-		return nil, fmt.Errorf("zendesk-connector: failed to add team member to a group: %s", err.Error())
+		return nil, nil, fmt.Errorf("zendesk-connector: failed to add team member to a group: %w", err)
+	}
+
+	g.users.invalidate(userID)
+	if g.sweep != nil {
+		g.sweep.addGroupMembership(membership{id: created.ID, parentID: groupID, userID: userID})
 	}
 
 	l.Warn("Membership has been created.",
-		zap.Int64("ID", membership.ID),
-		zap.Int64("UserID", membership.UserID),
-		zap.Int64("GroupID", membership.GroupID),
-		zap.Time("CreatedAt", membership.CreatedAt),
+		zap.Int64("ID", created.ID),
+		zap.Int64("UserID", created.UserID),
+		zap.Int64("GroupID", created.GroupID),
+		zap.Time("CreatedAt", created.CreatedAt),
 	)
 
-	return nil, nil
+	return groupGranted(entitlement.Resource, user, created.ID)
 }
 
 func (g *groupResourceType) Revoke(ctx context.Context, grant *v2.Grant) (annotations.Annotations, error) {
@@ -195,7 +428,7 @@ func (g *groupResourceType) Revoke(ctx context.Context, grant *v2.Grant) (annota
 		return nil, fmt.Errorf("zendesk-connector: only team members can have group membership revoked")
 	}
 
-	userID, err := strconv.ParseInt(principal.Id.Resource, 10, 64)
+	userID, err := resolveUserID(ctx, g.client, principal)
 	if err != nil {
 		return nil, err
 	}
@@ -205,25 +438,137 @@ func (g *groupResourceType) Revoke(ctx context.Context, grant *v2.Grant) (annota
 		return nil, err
 	}
 
+	err = g.checkGovernedGroup(ctx, groupID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Grants returned by Grant record the ID of the membership, which saves looking it up.
+	membershipID, hasMembershipID := grantMembershipID(grant)
+
+	switch {
+	case g.batch != nil:
+		_, err = g.batch.removeGroups.submit(ctx, groupID, userID, membershipID)
+		if errors.Is(err, client.ErrMembershipNotFound) {
+			return groupAlreadyRevoked(ctx, userID, groupID), nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("zendesk-connector: failed to revoke team member: %w", err)
+		}
+
+		l.Warn("Membership has been revoked..",
+			zap.Int64("UserID", userID),
+			zap.Int64("GroupID", groupID),
+		)
+	case hasMembershipID:
+		err = g.client.DeleteGroupMembership(ctx, membershipID)
+		if client.IsNotFound(err) {
+			// The recorded membership is stale once the user was removed and added again, so the current one is looked up.
+			err = g.revokeGroupMembership(ctx, userID, groupID)
+		} else if err == nil {
+			l.Warn("Membership has been revoked..",
+				zap.Int64("groupMembershipID", membershipID),
+			)
+		}
+		if errors.Is(err, client.ErrMembershipNotFound) {
+			return groupAlreadyRevoked(ctx, userID, groupID), nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("zendesk-connector: failed to revoke team member: %w", err)
+		}
+	default:
+		err = g.revokeGroupMembership(ctx, userID, groupID)
+		if errors.Is(err, client.ErrMembershipNotFound) {
+			return groupAlreadyRevoked(ctx, userID, groupID), nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("zendesk-connector: failed to revoke team member: %w", err)
+		}
+	}
+
+	g.users.invalidate(userID)
+	if g.sweep != nil {
+		g.sweep.removeGroupMembership(groupID, userID)
+	}
+
+	return nil, nil
+}
+
+// revokeGroupMembership looks up the membership of a user in a group and removes it. It returns
+// client.ErrMembershipNotFound if the user is not a member.
+func (g *groupResourceType) revokeGroupMembership(ctx context.Context, userID int64, groupID int64) error {
 	groupMembershipOptions := zendesk.GroupMembership{
 		UserID:  userID,
 		GroupID: groupID,
 	}
 	groupMembershipID, err := g.client.RemoveGroupMembershipByID(ctx, groupMembershipOptions)
+	if client.IsNotFound(err) {
+		return client.ErrMembershipNotFound
+	}
 	if err != nil {
-		return nil, fmt.Errorf("zendesk-connector: failed to revoke team member: %s", err.Error())
+		return err
 	}
 
-	l.Warn("Membership has been revoked..",
+	ctxzap.Extract(ctx).Warn("Membership has been revoked..",
 		zap.String("groupMembershipID", groupMembershipID),
 	)
 
-	return nil, nil
+	return nil
+}
+
+// groupGranted returns the grants of a membership created for a team member.
+func groupGranted(resource *v2.Resource, user zendesk.User, membershipID int64) ([]*v2.Grant, annotations.Annotations, error) {
+	grants, err := groupMembershipGrants(resource, user, membershipID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("zendesk-connector: failed to create grants of group %s: %w", resource.Id.Resource, err)
+	}
+
+	return grants, nil, nil
+}
+
+// groupAlreadyGranted reports a grant of a membership that already exists along with its grants,
+// so that retried grants succeed.
+func groupAlreadyGranted(ctx context.Context, resource *v2.Resource, user zendesk.User, membershipID int64) ([]*v2.Grant, annotations.Annotations, error) {
+	ctxzap.Extract(ctx).Warn("team member is already a member of the group",
+		zap.Int64("UserID", user.ID),
+		zap.String("GroupID", resource.Id.Resource),
+	)
+
+	grants, _, err := groupGranted(resource, user, membershipID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return grants, annotations.New(&v2.GrantAlreadyExists{}), nil
+}
+
+// groupAlreadyRevoked reports a revoke of a membership that does not exist, so that retried revokes succeed.
+func groupAlreadyRevoked(ctx context.Context, userID int64, groupID int64) annotations.Annotations {
+	ctxzap.Extract(ctx).Warn("team member is not a member of the group",
+		zap.Int64("UserID", userID),
+		zap.Int64("GroupID", groupID),
+	)
+
+	return annotations.New(&v2.GrantAlreadyRevoked{})
 }
 
-func groupBuilder(c *client.ZendeskClient) *groupResourceType {
+func groupBuilder(
+	c *client.ZendeskClient,
+	users *userDirectory,
+	members *teamMemberFilter,
+	filter *groupFilter,
+	prefetch *prefetcher,
+	sweep *membershipSweep,
+	batch *membershipBatcher,
+) *groupResourceType {
 	return &groupResourceType{
 		resourceType: resourceTypeGroup,
 		client:       c,
+		users:        users,
+		members:      members,
+		filter:       filter,
+		prefetch:     prefetch,
+		sweep:        sweep,
+		batch:        batch,
 	}
 }
@@ -1,7 +1,10 @@
 package connector
 
 import (
+	"context"
 	"fmt"
+	"maps"
+	"net/url"
 	"strconv"
 	"strings"
 	"time"
@@ -10,10 +13,14 @@ import (
 	"github.com/conductorone/baton-sdk/pkg/annotations"
 	"github.com/conductorone/baton-sdk/pkg/pagination"
 	ent "github.com/conductorone/baton-sdk/pkg/types/entitlement"
+	"github.com/conductorone/baton-sdk/pkg/types/grant"
 	rs "github.com/conductorone/baton-sdk/pkg/types/resource"
 	"github.com/nukosuke/go-zendesk/zendesk"
 	"golang.org/x/text/cases"
 	"golang.org/x/text/language"
+	"google.golang.org/protobuf/types/known/structpb"
+
+	"github.com/conductorone/baton-zendesk/pkg/client"
 )
 
 func v1AnnotationsForResourceType(resourceTypeID string) annotations.Annotations {
@@ -72,7 +79,7 @@ func PopulateOptions(displayName, permission, resource string) []ent.Entitlement
 }
 
 // getUserRoleResource creates a new connector resource for a Zendesk user.
-func getUserRoleResource(user *zendesk.User, resourceTypeTeam *v2.ResourceType) (*v2.Resource, error) {
+func getUserRoleResource(user *zendesk.User, traits *userTraitConfig, resourceTypeTeam *v2.ResourceType) (*v2.Resource, error) {
 	firstname, lastname := splitFullName(user.Name)
 	profile := map[string]interface{}{
 		"user_id":    user.ID,
@@ -80,30 +87,30 @@ func getUserRoleResource(user *zendesk.User, resourceTypeTeam *v2.ResourceType)
 		"last_name":  lastname,
 		"login":      user.Email,
 	}
+	addSecurityProfile(profile, user)
 
-	accountType := v2.UserTrait_ACCOUNT_TYPE_HUMAN
-	var status v2.UserTrait_Status_Status
-	switch user.Suspended {
-	case true:
+	status := v2.UserTrait_Status_STATUS_ENABLED
+	if !user.Active || user.Suspended {
 		status = v2.UserTrait_Status_STATUS_DISABLED
-	case false:
-		status = v2.UserTrait_Status_STATUS_ENABLED
-	default:
-		status = v2.UserTrait_Status_STATUS_UNSPECIFIED
 	}
 
 	userTraitOptions := []rs.UserTraitOption{
-		rs.WithUserProfile(profile),
 		rs.WithEmail(user.Email, true),
 		rs.WithStatus(status),
-		rs.WithAccountType(accountType),
 	}
+	userTraitOptions = append(userTraitOptions, traits.securityTraitOptions(user)...)
+	accountTypeOptions, accountTypeProfile := traits.accountTypeTraitOptions(user)
+	userTraitOptions = append(userTraitOptions, accountTypeOptions...)
+	maps.Copy(profile, accountTypeProfile)
+	addExternalIDProfile(profile, user.ExternalID)
+	userTraitOptions = append(userTraitOptions, rs.WithUserProfile(profile))
 
 	ret, err := rs.NewUserResource(
 		user.Name,
 		resourceTypeTeam,
 		user.ID,
 		userTraitOptions,
+		externalIDOptions(user.ExternalID)...,
 	)
 	if err != nil {
 		return nil, err
@@ -128,21 +135,20 @@ func splitFullName(name string) (string, string) {
 	return firstName, lastName
 }
 
-// isValidTeamMember checks team members.
-func isValidTeamMember(user *zendesk.User) bool {
-	if user.Role == "agent" || user.Role == "admin" && !user.Suspended { // team member
-		return true
-	}
+const identityTypeEmail = "email"
 
-	return false
+// addSecurityProfile records the two-factor state of a user and flags admins without it.
+func addSecurityProfile(profile map[string]interface{}, user *zendesk.User) {
+	profile["two_factor_auth_enabled"] = user.TwoFactorAuthEnabled
+	profile["admin_without_mfa"] = user.Role == teamRoleAdmin && !user.TwoFactorAuthEnabled
 }
 
 // getUserSupportRoles gets user roles.
-func getUserSupportRoles(users []zendesk.User) map[string]int64 {
+func getUserSupportRoles(users []zendesk.User, members *teamMemberFilter) map[string]int64 {
 	var supportRoles = make(map[string]int64)
 	for _, user := range users {
 		userCopy := user
-		if isValidTeamMember(&userCopy) { // only team member
+		if members.includes(&userCopy) { // only team member
 			supportRoles[user.Role] = user.ID
 		}
 	}
@@ -150,7 +156,16 @@ func getUserSupportRoles(users []zendesk.User) map[string]int64 {
 	return supportRoles
 }
 
-func getTeamResource(user *zendesk.User, resourceTypeTeam *v2.ResourceType) (*v2.Resource, error) {
+// getTeamResource creates a new connector resource for a team member. Sessions are nil when they are not synced,
+// which leaves the session count out of the profile.
+func getTeamResource(
+	user *zendesk.User,
+	identities []client.UserIdentity,
+	sessions []client.Session,
+	userFields map[string]interface{},
+	traits *userTraitConfig,
+	resourceTypeTeam *v2.ResourceType,
+) (*v2.Resource, error) {
 	var userStatus v2.UserTrait_Status_Status = v2.UserTrait_Status_STATUS_ENABLED
 	firstName, lastName := splitFullName(user.Name)
 	profile := map[string]interface{}{
@@ -159,16 +174,46 @@ func getTeamResource(user *zendesk.User, resourceTypeTeam *v2.ResourceType) (*v2
 		"last_name":  lastName,
 		"email":      user.Email,
 	}
+	addSecurityProfile(profile, user)
 	if !user.Active || user.Suspended {
 		userStatus = v2.UserTrait_Status_STATUS_DISABLED
 	}
 
 	userTraits := []rs.UserTraitOption{
-		rs.WithUserProfile(profile),
 		rs.WithStatus(userStatus),
 		rs.WithUserLogin(user.Email),
 		rs.WithEmail(user.Email, true),
 	}
+	userTraits = append(userTraits, traits.securityTraitOptions(user)...)
+	accountTypeOptions, accountTypeProfile := traits.accountTypeTraitOptions(user)
+	userTraits = append(userTraits, accountTypeOptions...)
+	maps.Copy(profile, accountTypeProfile)
+
+	if len(identities) > 0 {
+		profileIdentities := make([]interface{}, 0, len(identities))
+		for _, identity := range identities {
+			profileIdentities = append(profileIdentities, map[string]interface{}{
+				"type":     identity.Type,
+				"value":    identity.Value,
+				"verified": identity.Verified,
+				"primary":  identity.Primary,
+			})
+
+			// Every verified email other than the primary one is added as a secondary email.
+			if identity.Type == identityTypeEmail && identity.Verified && !strings.EqualFold(identity.Value, user.Email) {
+				userTraits = append(userTraits, rs.WithEmail(identity.Value, false))
+			}
+		}
+		profile["identities"] = profileIdentities
+	}
+	if sessions != nil {
+		addSessionProfile(profile, sessions)
+	}
+	addExternalIDProfile(profile, user.ExternalID)
+	if len(userFields) > 0 {
+		profile["user_fields"] = userFields
+	}
+	userTraits = append(userTraits, rs.WithUserProfile(profile))
 
 	if user.LastLoginAt.String() != "" {
 		loginTime, err := time.Parse("2006-01-02T15:04:05Z", user.LastLoginAt.String())
@@ -189,7 +234,7 @@ func getTeamResource(user *zendesk.User, resourceTypeTeam *v2.ResourceType) (*v2
 		displayName = user.Email
 	}
 
-	ret, err := rs.NewUserResource(displayName, resourceTypeTeam, user.ID, userTraits)
+	ret, err := rs.NewUserResource(displayName, resourceTypeTeam, user.ID, userTraits, externalIDOptions(user.ExternalID)...)
 	if err != nil {
 		return nil, err
 	}
@@ -218,35 +263,33 @@ func getGroupResource(group zendesk.Group, resourceTypeGroup *v2.ResourceType, p
 	return ret, nil
 }
 
-// getUserResource gets a new connector resource for a Zenddesk group.
+// getUserResource gets a new connector resource for a Zendesk user, including end-users.
 func getUserResource(user zendesk.User, resourceTypeUser *v2.ResourceType) (*v2.Resource, error) {
-	resource, err := rs.NewUserResource(user.Name, resourceTypeUser, user.ID, nil)
-	if err != nil {
-		return nil, err
+	status := v2.UserTrait_Status_STATUS_ENABLED
+	if !user.Active || user.Suspended {
+		status = v2.UserTrait_Status_STATUS_DISABLED
 	}
 
-	return resource, nil
-}
-
-// getUserByID gets an user by ID.
-func getUserByID(userID int64, users map[int64]zendesk.User) zendesk.User {
-	if user, ok := users[userID]; ok {
-		return user
+	profile := map[string]interface{}{
+		"login": user.Email,
+		"role":  user.Role,
 	}
+	addExternalIDProfile(profile, user.ExternalID)
 
-	return zendesk.User{}
-}
+	userTraitOptions := []rs.UserTraitOption{
+		rs.WithStatus(status),
+		rs.WithUserProfile(profile),
+	}
+	if user.Email != "" {
+		userTraitOptions = append(userTraitOptions, rs.WithEmail(user.Email, true), rs.WithUserLogin(user.Email))
+	}
 
-// getOrganizationMembers gets organization members.
-func getOrganizationMembers(orgID int64, users map[int64]zendesk.User) []zendesk.User {
-	var members []zendesk.User
-	for _, user := range users {
-		if user.OrganizationID == orgID {
-			members = append(members, user)
-		}
+	resource, err := rs.NewUserResource(user.Name, resourceTypeUser, user.ID, userTraitOptions, externalIDOptions(user.ExternalID)...)
+	if err != nil {
+		return nil, err
 	}
 
-	return members
+	return resource, nil
 }
 
 // getRoleResource creates a new connector resource for a Zendesk role.
@@ -273,3 +316,406 @@ func getRoleResource(role *zendesk.CustomRole, resourceTypeRole *v2.ResourceType
 
 	return ret, nil
 }
+
+// getAPITokenResource creates a new connector resource for a Zendesk API token.
+func getAPITokenResource(token *client.APIToken, current bool, resourceTypeAPIToken *v2.ResourceType) (*v2.Resource, error) {
+	profile := map[string]interface{}{
+		"token_id":    token.ID,
+		"description": token.Description,
+		"active":      token.Active,
+		"current":     current,
+	}
+
+	secretTraitOptions := []rs.SecretTraitOption{
+		withSecretProfile(profile),
+	}
+	if !token.CreatedAt.IsZero() {
+		secretTraitOptions = append(secretTraitOptions, rs.WithSecretCreatedAt(token.CreatedAt))
+	}
+	if !token.LastUsedAt.IsZero() {
+		secretTraitOptions = append(secretTraitOptions, rs.WithSecretLastUsedAt(token.LastUsedAt))
+	}
+
+	displayName := token.Description
+	if displayName == "" {
+		displayName = fmt.Sprintf("API Token %d", token.ID)
+	}
+
+	ret, err := rs.NewSecretResource(
+		displayName,
+		resourceTypeAPIToken,
+		token.ID,
+		secretTraitOptions,
+		rs.WithDescription(token.Description),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return ret, nil
+}
+
+// getOAuthClientResource creates a new connector resource for a Zendesk OAuth client.
+func getOAuthClientResource(oauthClient *client.OAuthClient, resourceTypeOAuthClient *v2.ResourceType) (*v2.Resource, error) {
+	profile := map[string]interface{}{
+		"client_id":   oauthClient.ID,
+		"identifier":  oauthClient.Identifier,
+		"company":     oauthClient.Company,
+		"description": oauthClient.Description,
+		"kind":        oauthClient.Kind,
+		"user_id":     oauthClient.UserID,
+	}
+
+	ret, err := rs.NewAppResource(
+		oauthClient.Name,
+		resourceTypeOAuthClient,
+		oauthClient.ID,
+		[]rs.AppTraitOption{rs.WithAppProfile(profile)},
+		rs.WithDescription(oauthClient.Description),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return ret, nil
+}
+
+// getOAuthTokenResource creates a new connector resource for a Zendesk OAuth token.
+func getOAuthTokenResource(token *client.OAuthToken, resourceTypeOAuthToken *v2.ResourceType) (*v2.Resource, error) {
+	scopes := make([]interface{}, 0, len(token.Scopes))
+	for _, scope := range token.Scopes {
+		scopes = append(scopes, scope)
+	}
+	profile := map[string]interface{}{
+		"token_id":  token.ID,
+		"client_id": token.ClientID,
+		"user_id":   token.UserID,
+		"scopes":    scopes,
+	}
+
+	secretTraitOptions := []rs.SecretTraitOption{
+		withSecretProfile(profile),
+	}
+	if token.UserID != 0 {
+		userID, err := rs.NewResourceID(resourceTypeTeam, token.UserID)
+		if err != nil {
+			return nil, err
+		}
+		secretTraitOptions = append(secretTraitOptions, rs.WithSecretIdentityID(userID))
+	}
+	if !token.CreatedAt.IsZero() {
+		secretTraitOptions = append(secretTraitOptions, rs.WithSecretCreatedAt(token.CreatedAt))
+	}
+	if !token.UsedAt.IsZero() {
+		secretTraitOptions = append(secretTraitOptions, rs.WithSecretLastUsedAt(token.UsedAt))
+	}
+	if !token.ExpiresAt.IsZero() {
+		secretTraitOptions = append(secretTraitOptions, rs.WithSecretExpiresAt(token.ExpiresAt))
+	}
+
+	ret, err := rs.NewSecretResource(
+		fmt.Sprintf("OAuth Token %d", token.ID),
+		resourceTypeOAuthToken,
+		token.ID,
+		secretTraitOptions,
+		rs.WithDescription(strings.Join(token.Scopes, " ")),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return ret, nil
+}
+
+// getAppInstallationResource creates a new connector resource for an installed Zendesk app.
+func getAppInstallationResource(installation *client.AppInstallation, resourceTypeAppInstallation *v2.ResourceType) (*v2.Resource, error) {
+	profile := map[string]interface{}{
+		"installation_id": installation.ID,
+		"app_id":          installation.AppID,
+		"product":         installation.Product,
+		"enabled":         installation.Enabled,
+		"restricted":      len(installation.RoleRestrictions) > 0 || len(installation.GroupRestrictions) > 0,
+	}
+
+	displayName := installation.Settings.Title
+	if displayName == "" {
+		displayName = installation.Settings.Name
+	}
+	if displayName == "" {
+		displayName = fmt.Sprintf("App Installation %d", installation.ID)
+	}
+
+	ret, err := rs.NewAppResource(
+		displayName,
+		resourceTypeAppInstallation,
+		installation.ID,
+		[]rs.AppTraitOption{rs.WithAppProfile(profile)},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return ret, nil
+}
+
+// getWebhookResource creates a new connector resource for a Zendesk webhook.
+// Only the kind of authentication and whether a signing secret exists are recorded, never their values.
+func getWebhookResource(webhook *zendesk.Webhook, signingSecretPresent bool, resourceTypeWebhook *v2.ResourceType) (*v2.Resource, error) {
+	authType := "none"
+	if webhook.Authentication != nil && webhook.Authentication.Type != "" {
+		authType = webhook.Authentication.Type
+	}
+
+	subscriptions := make([]interface{}, 0, len(webhook.Subscriptions))
+	for _, subscription := range webhook.Subscriptions {
+		subscriptions = append(subscriptions, subscription)
+	}
+
+	profile := map[string]interface{}{
+		"webhook_id":             webhook.ID,
+		"endpoint_host":          endpointHost(webhook.Endpoint),
+		"http_method":            webhook.HTTPMethod,
+		"request_format":         webhook.RequestFormat,
+		"auth_type":              authType,
+		"status":                 webhook.Status,
+		"created_by":             webhook.CreatedBy,
+		"updated_by":             webhook.UpdatedBy,
+		"signing_secret_present": signingSecretPresent,
+		"subscriptions":          subscriptions,
+	}
+	if !webhook.CreatedAt.IsZero() {
+		profile["created_at"] = webhook.CreatedAt.Format(time.RFC3339)
+	}
+
+	ret, err := rs.NewAppResource(
+		webhook.Name,
+		resourceTypeWebhook,
+		webhook.ID,
+		[]rs.AppTraitOption{rs.WithAppProfile(profile)},
+		rs.WithDescription(webhook.Description),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return ret, nil
+}
+
+// getTargetResource creates a new connector resource for a legacy Zendesk target.
+// The basic auth password is never recorded.
+func getTargetResource(target *zendesk.Target, resourceTypeTarget *v2.ResourceType) (*v2.Resource, error) {
+	authType := "none"
+	if target.Username != "" {
+		authType = "basic_auth"
+	}
+
+	profile := map[string]interface{}{
+		"target_id":     target.ID,
+		"type":          target.Type,
+		"active":        target.Active,
+		"endpoint_host": endpointHost(target.TargetURL),
+		"method":        target.Method,
+		"content_type":  target.ContentType,
+		"email":         target.Email,
+		"auth_type":     authType,
+	}
+	if target.CreatedAt != nil {
+		profile["created_at"] = target.CreatedAt.Format(time.RFC3339)
+	}
+
+	ret, err := rs.NewAppResource(
+		target.Title,
+		resourceTypeTarget,
+		target.ID,
+		[]rs.AppTraitOption{rs.WithAppProfile(profile)},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return ret, nil
+}
+
+// getTagResource creates a new connector resource for a Zendesk tag.
+func getTagResource(tag *client.Tag, resourceTypeTag *v2.ResourceType) (*v2.Resource, error) {
+	profile := map[string]interface{}{
+		"tag": tag.Name,
+	}
+
+	ret, err := rs.NewGroupResource(
+		tag.Name,
+		resourceTypeTag,
+		tag.Name,
+		[]rs.GroupTraitOption{rs.WithGroupProfile(profile)},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return ret, nil
+}
+
+// endpointHost returns the host of an endpoint URL, dropping any credentials, path and query.
+func endpointHost(endpoint string) string {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return ""
+	}
+
+	return u.Host
+}
+
+// appendMissingID appends id to ids unless it is already present.
+func appendMissingID(ids []int64, id int64) []int64 {
+	for _, existing := range ids {
+		if existing == id {
+			return ids
+		}
+	}
+
+	return append(ids, id)
+}
+
+// removeID returns a copy of ids without id.
+func removeID(ids []int64, id int64) []int64 {
+	ret := make([]int64, 0, len(ids))
+	for _, existing := range ids {
+		if existing != id {
+			ret = append(ret, existing)
+		}
+	}
+
+	return ret
+}
+
+// withSecretProfile sets the profile of a secret trait, the SDK has no option for it.
+func withSecretProfile(profile map[string]interface{}) rs.SecretTraitOption {
+	return func(t *v2.SecretTrait) error {
+		p, err := structpb.NewStruct(profile)
+		if err != nil {
+			return err
+		}
+
+		t.Profile = p
+		return nil
+	}
+}
+
+// addSessionProfile records how many active sessions a user has and when any of them was last seen.
+func addSessionProfile(profile map[string]interface{}, sessions []client.Session) {
+	profile[profileSessionCount] = int64(len(sessions))
+
+	var lastSeenAt time.Time
+	for _, session := range sessions {
+		if session.LastSeenAt.After(lastSeenAt) {
+			lastSeenAt = session.LastSeenAt
+		}
+	}
+	if !lastSeenAt.IsZero() {
+		profile["last_seen_at"] = lastSeenAt.Format(time.RFC3339)
+	}
+}
+
+// addExternalIDProfile records the external ID set by an identity provider or CRM in a profile.
+func addExternalIDProfile(profile map[string]interface{}, externalID string) {
+	if externalID != "" {
+		profile["external_id"] = externalID
+	}
+}
+
+// externalIDOptions sets the external ID of a resource and annotates it with it, if it has one.
+func externalIDOptions(externalID string) []rs.ResourceOption {
+	if externalID == "" {
+		return nil
+	}
+
+	return []rs.ResourceOption{
+		rs.WithExternalID(&v2.ExternalId{Id: externalID}),
+		rs.WithAnnotation(&v2.ExternalId{Id: externalID}),
+	}
+}
+
+// grantMembershipIDKey is the grant metadata field holding the ID of the Zendesk membership behind a grant.
+const grantMembershipIDKey = "membership_id"
+
+// withMembershipID records the ID of the Zendesk membership behind a grant in its metadata, so that revoking
+// the grant deletes the membership without looking it up. Grants of unknown memberships are left as they are.
+func withMembershipID(membershipID int64) grant.GrantOption {
+	return func(g *v2.Grant) error {
+		if membershipID == 0 {
+			return nil
+		}
+
+		return grant.WithGrantMetadata(map[string]interface{}{
+			grantMembershipIDKey: strconv.FormatInt(membershipID, 10),
+		})(g)
+	}
+}
+
+// grantMembershipID returns the ID of the Zendesk membership recorded in the metadata of a grant, if any.
+func grantMembershipID(g *v2.Grant) (int64, bool) {
+	metadata := &v2.GrantMetadata{}
+	annos := annotations.Annotations(g.GetAnnotations())
+	if ok, err := annos.Pick(metadata); err != nil || !ok {
+		return 0, false
+	}
+
+	value := metadata.GetMetadata().GetFields()[grantMembershipIDKey].GetStringValue()
+	membershipID, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || membershipID == 0 {
+		return 0, false
+	}
+
+	return membershipID, true
+}
+
+// resolveUserID returns the Zendesk user ID of a principal. Principals that do not carry a numeric Zendesk ID
+// are looked up by the external ID in their annotations, or by their resource ID.
+func resolveUserID(ctx context.Context, c *client.ZendeskClient, principal *v2.Resource) (int64, error) {
+	userID, err := strconv.ParseInt(principal.Id.Resource, 10, 64)
+	if err == nil {
+		return userID, nil
+	}
+
+	externalID := principal.Id.Resource
+	if id := principal.GetExternalId().GetId(); id != "" {
+		externalID = id
+	}
+	annos := annotations.Annotations(principal.Annotations)
+	annotated := &v2.ExternalId{}
+	if ok, err := annos.Pick(annotated); err == nil && ok && annotated.Id != "" {
+		externalID = annotated.Id
+	}
+
+	user, err := c.FindUserByExternalID(ctx, externalID)
+	if err != nil {
+		return 0, fmt.Errorf("zendesk-connector: failed to resolve principal %s: %w", principal.Id.Resource, err)
+	}
+
+	return user.ID, nil
+}
+
+// searchQuery joins a configured search query with the search terms matching any of the values of a property,
+// which Zendesk combines with OR when they name the same property. Queries already naming the property are kept as
+// they are, since the terms added would widen them.
+func searchQuery(query string, property string, values []string) string {
+	query = strings.TrimSpace(query)
+	if queryNamesProperty(query, property) {
+		return query
+	}
+
+	terms := make([]string, 0, len(values)+1)
+	if query != "" {
+		terms = append(terms, query)
+	}
+	for _, value := range values {
+		terms = append(terms, fmt.Sprintf("%s:%q", property, value))
+	}
+
+	return strings.Join(terms, " ")
+}
+
+// queryNamesProperty reports whether a search query has a term on a property.
+func queryNamesProperty(query string, property string) bool {
+	return strings.HasPrefix(query, property+":") || strings.Contains(query, " "+property+":")
+}
@@ -0,0 +1,669 @@
+package connector
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"sync"
+
+	"github.com/conductorone/baton-zendesk/pkg/client"
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"github.com/nukosuke/go-zendesk/zendesk"
+	"go.uber.org/zap"
+)
+
+const (
+	// sweepSpillAfter is the number of users, and of memberships, kept in memory by a membership index before they
+	// are moved to temporary files.
+	sweepSpillAfter = 20000
+	// sweepPageSize is the number of memberships in a page of grants served from a membership index.
+	sweepPageSize = 100
+	// membershipRecordSize is the size of a membership in a temporary file: its parent, user and membership IDs.
+	membershipRecordSize = 24
+)
+
+// membershipSweep serves group and organization grants from indexes built by listing the memberships of
+// the whole account once, instead of listing the members of every group and organization.
+// Indexes are rebuilt on the next Grants call after a sync starts listing the resources, while provisioning updates
+// them in place. A replaced index is closed once the Grants calls still reading it are done. The temporary files of
+// indexes hold personal data, so they are removed when the connector shuts down.
+type membershipSweep struct {
+	client     *client.ZendeskClient
+	spillAfter int
+
+	mu     sync.Mutex
+	groups *membershipIndex
+	orgs   *membershipIndex
+}
+
+// newMembershipSweep returns a sweep whose indexes are dropped once ctx, which lives as long as the connector, is done.
+func newMembershipSweep(ctx context.Context, c *client.ZendeskClient) *membershipSweep {
+	s := &membershipSweep{
+		client:     c,
+		spillAfter: sweepSpillAfter,
+	}
+	context.AfterFunc(ctx, func() {
+		s.invalidateGroups(ctx)
+		s.invalidateOrgs(ctx)
+	})
+
+	return s
+}
+
+// membershipIndex holds the memberships of every group or organization, and the members themselves.
+type membershipIndex struct {
+	memberships *membershipStore
+	users       *userStore
+
+	mu sync.Mutex
+	// refs counts the Grants calls reading the index, plus one for the sweep while the index is current.
+	refs int
+	// added holds the memberships provisioned since the sweep by parent, and removed the swept memberships that
+	// were revoked or provisioned again since.
+	added   map[int64][]membership
+	removed map[membershipKey]struct{}
+}
+
+// membershipKey identifies a membership by its parent and member, since revoked grants may not carry its ID.
+type membershipKey struct {
+	parentID int64
+	userID   int64
+}
+
+func newMembershipIndex(spillAfter int) *membershipIndex {
+	return &membershipIndex{
+		memberships: newMembershipStore(spillAfter),
+		users:       newUserStore(spillAfter),
+		refs:        1,
+		added:       make(map[int64][]membership),
+		removed:     make(map[membershipKey]struct{}),
+	}
+}
+
+// page returns a page of the memberships of a parent starting at offset, along with the offset of the next page.
+// Memberships provisioned since the sweep follow the swept ones. Members that were not side-loaded are missing
+// from the users of the page.
+func (idx *membershipIndex) page(parentID int64, offset int) ([]membership, map[int64]zendesk.User, string, error) {
+	swept, total, err := idx.memberships.page(parentID, offset, sweepPageSize)
+	if err != nil {
+		return nil, nil, "", err
+	}
+
+	idx.mu.Lock()
+	memberships := make([]membership, 0, len(swept))
+	for _, m := range swept {
+		if _, ok := idx.removed[membershipKey{parentID: m.parentID, userID: m.userID}]; !ok {
+			memberships = append(memberships, m)
+		}
+	}
+
+	added := idx.added[parentID]
+	next := offset + len(swept)
+	if len(swept) < sweepPageSize {
+		start := max(offset-total, 0)
+		end := min(start+sweepPageSize-len(swept), len(added))
+		if start < end {
+			memberships = append(memberships, added[start:end]...)
+			next = total + end
+		}
+	}
+	more := next < total+len(added)
+	idx.mu.Unlock()
+
+	var nextPageToken string
+	if more {
+		nextPageToken = strconv.Itoa(next)
+	}
+
+	users := make(map[int64]zendesk.User, len(memberships))
+	for _, m := range memberships {
+		user, ok, err := idx.users.get(m.userID)
+		if err != nil {
+			return nil, nil, "", err
+		}
+		if ok {
+			users[m.userID] = user
+		}
+	}
+
+	return memberships, users, nextPageToken, nil
+}
+
+// add records a membership provisioned since the sweep, replacing any swept membership of the user.
+func (idx *membershipIndex) add(m membership) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	idx.removed[membershipKey{parentID: m.parentID, userID: m.userID}] = struct{}{}
+	idx.added[m.parentID] = append(slices.DeleteFunc(idx.added[m.parentID], func(added membership) bool {
+		return added.userID == m.userID
+	}), m)
+}
+
+// remove records a membership revoked since the sweep.
+func (idx *membershipIndex) remove(parentID int64, userID int64) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	idx.removed[membershipKey{parentID: parentID, userID: userID}] = struct{}{}
+	idx.added[parentID] = slices.DeleteFunc(idx.added[parentID], func(added membership) bool {
+		return added.userID == userID
+	})
+}
+
+func (idx *membershipIndex) acquire() {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	idx.refs++
+}
+
+// release drops a reference to the index, and closes it once the last one is dropped.
+func (idx *membershipIndex) release(ctx context.Context) {
+	idx.mu.Lock()
+	idx.refs--
+	refs := idx.refs
+	idx.mu.Unlock()
+
+	if refs > 0 {
+		return
+	}
+
+	err := idx.close()
+	if err != nil {
+		ctxzap.Extract(ctx).Error("zendesk-connector: failed to close temporary membership files", zap.Error(err))
+	}
+}
+
+// close removes the temporary files of the index, if any.
+func (idx *membershipIndex) close() error {
+	return errors.Join(idx.memberships.close(), idx.users.close())
+}
+
+// membership is a user belonging to a group or organization.
+type membership struct {
+	id       int64
+	parentID int64
+	userID   int64
+}
+
+type listMembershipsFunc func(ctx context.Context, cursor string) ([]membership, []zendesk.User, string, error)
+
+// groupIndex returns the group membership index, sweeping the memberships first unless they were already.
+// The caller releases the index once done reading it.
+func (s *membershipSweep) groupIndex(ctx context.Context) (*membershipIndex, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.groups == nil {
+		idx, err := s.sweep(ctx, func(ctx context.Context, cursor string) ([]membership, []zendesk.User, string, error) {
+			groupMemberships, users, nextCursor, err := s.client.ListAllGroupMemberships(ctx, cursor)
+			if err != nil {
+				return nil, nil, "", fmt.Errorf("zendesk-connector: failed to list group memberships: %w", err)
+			}
+
+			memberships := make([]membership, 0, len(groupMemberships))
+			for _, m := range groupMemberships {
+				memberships = append(memberships, membership{id: m.ID, parentID: m.GroupID, userID: m.UserID})
+			}
+
+			return memberships, users, nextCursor, nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		s.groups = idx
+	}
+	s.groups.acquire()
+
+	return s.groups, nil
+}
+
+// orgIndex returns the organization membership index, sweeping the memberships first unless they were already.
+// The caller releases the index once done reading it.
+func (s *membershipSweep) orgIndex(ctx context.Context) (*membershipIndex, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.orgs == nil {
+		idx, err := s.sweep(ctx, func(ctx context.Context, cursor string) ([]membership, []zendesk.User, string, error) {
+			orgMemberships, users, nextCursor, err := s.client.ListAllOrganizationMemberships(ctx, cursor)
+			if err != nil {
+				return nil, nil, "", fmt.Errorf("zendesk-connector: failed to list organization memberships: %w", err)
+			}
+
+			memberships := make([]membership, 0, len(orgMemberships))
+			for _, m := range orgMemberships {
+				memberships = append(memberships, membership{id: m.ID, parentID: m.OrganizationID, userID: m.UserID})
+			}
+
+			return memberships, users, nextCursor, nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		s.orgs = idx
+	}
+	s.orgs.acquire()
+
+	return s.orgs, nil
+}
+
+// sweep lists every page of memberships and buckets them by parent.
+func (s *membershipSweep) sweep(ctx context.Context, list listMembershipsFunc) (*membershipIndex, error) {
+	idx := newMembershipIndex(s.spillAfter)
+
+	var cursor string
+	for {
+		memberships, users, nextCursor, err := list(ctx, cursor)
+		if err != nil {
+			return nil, errors.Join(err, idx.close())
+		}
+
+		err = idx.memberships.add(memberships)
+		if err != nil {
+			return nil, errors.Join(err, idx.close())
+		}
+		err = idx.users.add(users)
+		if err != nil {
+			return nil, errors.Join(err, idx.close())
+		}
+
+		if nextCursor == "" {
+			break
+		}
+		cursor = nextCursor
+	}
+
+	err := idx.memberships.finish()
+	if err != nil {
+		return nil, errors.Join(err, idx.close())
+	}
+
+	return idx, nil
+}
+
+// invalidateGroups drops the group membership index, so that the next Grants call sweeps the memberships again.
+func (s *membershipSweep) invalidateGroups(ctx context.Context) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.groups != nil {
+		s.groups.release(ctx)
+		s.groups = nil
+	}
+}
+
+// invalidateOrgs drops the organization membership index, so that the next Grants call sweeps the memberships again.
+func (s *membershipSweep) invalidateOrgs(ctx context.Context) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.orgs != nil {
+		s.orgs.release(ctx)
+		s.orgs = nil
+	}
+}
+
+// addGroupMembership records a provisioned group membership in the group membership index, if it was swept.
+func (s *membershipSweep) addGroupMembership(m membership) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.groups != nil {
+		s.groups.add(m)
+	}
+}
+
+// removeGroupMembership records a revoked group membership in the group membership index, if it was swept.
+func (s *membershipSweep) removeGroupMembership(groupID int64, userID int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.groups != nil {
+		s.groups.remove(groupID, userID)
+	}
+}
+
+// addOrgMembership records a provisioned organization membership in the organization membership index, if it was
+// swept.
+func (s *membershipSweep) addOrgMembership(m membership) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.orgs != nil {
+		s.orgs.add(m)
+	}
+}
+
+// removeOrgMembership records a revoked organization membership in the organization membership index, if it was
+// swept.
+func (s *membershipSweep) removeOrgMembership(orgID int64, userID int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.orgs != nil {
+		s.orgs.remove(orgID, userID)
+	}
+}
+
+// userStore keeps users in memory until there are more than spillAfter of them, and in a temporary file after that.
+type userStore struct {
+	spillAfter int
+
+	mu      sync.Mutex
+	users   map[int64]zendesk.User
+	file    *os.File
+	size    int64
+	offsets map[int64]spilledUser
+}
+
+type spilledUser struct {
+	offset int64
+	length int
+}
+
+func newUserStore(spillAfter int) *userStore {
+	return &userStore{
+		spillAfter: spillAfter,
+		users:      make(map[int64]zendesk.User),
+	}
+}
+
+func (u *userStore) add(users []zendesk.User) error {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	if u.file == nil {
+		for _, user := range users {
+			u.users[user.ID] = user
+		}
+		if len(u.users) <= u.spillAfter {
+			return nil
+		}
+
+		file, err := createSpillFile("baton-zendesk-users-*.jsonl")
+		if err != nil {
+			return fmt.Errorf("zendesk-connector: failed to create temporary user file: %w", err)
+		}
+		u.file = file
+		u.offsets = make(map[int64]spilledUser, len(u.users))
+
+		users = make([]zendesk.User, 0, len(u.users))
+		for _, user := range u.users {
+			users = append(users, user)
+		}
+		u.users = nil
+	}
+
+	for _, user := range users {
+		if _, ok := u.offsets[user.ID]; ok {
+			continue
+		}
+
+		data, err := json.Marshal(user)
+		if err != nil {
+			return err
+		}
+		_, err = u.file.WriteAt(data, u.size)
+		if err != nil {
+			return fmt.Errorf("zendesk-connector: failed to write temporary user file: %w", err)
+		}
+
+		u.offsets[user.ID] = spilledUser{offset: u.size, length: len(data)}
+		u.size += int64(len(data))
+	}
+
+	return nil
+}
+
+// get returns a user of the store, and false if the user was not side-loaded with its memberships.
+func (u *userStore) get(userID int64) (zendesk.User, bool, error) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	if u.file == nil {
+		user, ok := u.users[userID]
+		return user, ok, nil
+	}
+
+	spilled, ok := u.offsets[userID]
+	if !ok {
+		return zendesk.User{}, false, nil
+	}
+
+	data := make([]byte, spilled.length)
+	_, err := u.file.ReadAt(data, spilled.offset)
+	if err != nil {
+		return zendesk.User{}, false, fmt.Errorf("zendesk-connector: failed to read temporary user file: %w", err)
+	}
+
+	var user zendesk.User
+	err = json.Unmarshal(data, &user)
+	if err != nil {
+		return zendesk.User{}, false, err
+	}
+
+	return user, true, nil
+}
+
+// close removes the temporary file of the store, if any.
+func (u *userStore) close() error {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	var err error
+	if u.file != nil {
+		err = u.file.Close()
+		u.file = nil
+		u.offsets = nil
+	}
+	u.users = make(map[int64]zendesk.User)
+
+	return err
+}
+
+// membershipStore keeps memberships in memory, bucketed by parent, until there are more than spillAfter of them.
+// After that they are appended to a temporary file, which is sorted by parent once every membership was added,
+// so that only the number of memberships of each parent stays in memory.
+type membershipStore struct {
+	spillAfter int
+
+	mu      sync.Mutex
+	count   int
+	parents map[int64][]membership
+	file    *os.File
+	spans   map[int64]membershipSpan
+}
+
+// membershipSpan locates the memberships of a parent in the temporary file, in records.
+type membershipSpan struct {
+	start int
+	count int
+}
+
+func newMembershipStore(spillAfter int) *membershipStore {
+	return &membershipStore{
+		spillAfter: spillAfter,
+		parents:    make(map[int64][]membership),
+	}
+}
+
+func (m *membershipStore) add(memberships []membership) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.count += len(memberships)
+	if m.file == nil {
+		for _, ms := range memberships {
+			m.parents[ms.parentID] = append(m.parents[ms.parentID], ms)
+		}
+		if m.count <= m.spillAfter {
+			return nil
+		}
+
+		file, err := createSpillFile("baton-zendesk-memberships-*.bin")
+		if err != nil {
+			return fmt.Errorf("zendesk-connector: failed to create temporary membership file: %w", err)
+		}
+		m.file = file
+		m.spans = make(map[int64]membershipSpan, len(m.parents))
+
+		memberships = make([]membership, 0, m.count)
+		for _, parent := range m.parents {
+			memberships = append(memberships, parent...)
+		}
+		m.parents = nil
+	}
+
+	data := make([]byte, 0, len(memberships)*membershipRecordSize)
+	for _, ms := range memberships {
+		data = binary.LittleEndian.AppendUint64(data, uint64(ms.parentID))
+		data = binary.LittleEndian.AppendUint64(data, uint64(ms.userID))
+		data = binary.LittleEndian.AppendUint64(data, uint64(ms.id))
+
+		span := m.spans[ms.parentID]
+		span.count++
+		m.spans[ms.parentID] = span
+	}
+	_, err := m.file.Write(data)
+	if err != nil {
+		return fmt.Errorf("zendesk-connector: failed to write temporary membership file: %w", err)
+	}
+
+	return nil
+}
+
+// finish sorts the memberships written to the temporary file by parent, once every membership was added.
+func (m *membershipStore) finish() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.file == nil {
+		return nil
+	}
+
+	parentIDs := make([]int64, 0, len(m.spans))
+	for parentID := range m.spans {
+		parentIDs = append(parentIDs, parentID)
+	}
+	slices.Sort(parentIDs)
+
+	start := 0
+	for _, parentID := range parentIDs {
+		span := m.spans[parentID]
+		span.start = start
+		start += span.count
+		m.spans[parentID] = span
+	}
+
+	sorted, err := createSpillFile("baton-zendesk-memberships-*.bin")
+	if err != nil {
+		return fmt.Errorf("zendesk-connector: failed to create temporary membership file: %w", err)
+	}
+
+	written := make(map[int64]int, len(m.spans))
+	chunk := make([]byte, sweepPageSize*membershipRecordSize)
+	for offset := int64(0); offset < int64(m.count)*membershipRecordSize; {
+		n, err := m.file.ReadAt(chunk, offset)
+		if err != nil && !errors.Is(err, io.EOF) {
+			return errors.Join(fmt.Errorf("zendesk-connector: failed to read temporary membership file: %w", err), sorted.Close())
+		}
+		offset += int64(n)
+
+		for record := chunk[:n]; len(record) >= membershipRecordSize; record = record[membershipRecordSize:] {
+			parentID := int64(binary.LittleEndian.Uint64(record))
+			position := m.spans[parentID].start + written[parentID]
+			written[parentID]++
+
+			_, err = sorted.WriteAt(record[:membershipRecordSize], int64(position)*membershipRecordSize)
+			if err != nil {
+				return errors.Join(fmt.Errorf("zendesk-connector: failed to write temporary membership file: %w", err), sorted.Close())
+			}
+		}
+	}
+
+	err = m.file.Close()
+	m.file = sorted
+
+	return err
+}
+
+// page returns up to size memberships of a parent starting at offset, along with the number of memberships of the parent.
+func (m *membershipStore) page(parentID int64, offset int, size int) ([]membership, int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.file == nil {
+		memberships := m.parents[parentID]
+		if offset >= len(memberships) {
+			return nil, len(memberships), nil
+		}
+
+		return memberships[offset:min(offset+size, len(memberships))], len(memberships), nil
+	}
+
+	span := m.spans[parentID]
+	if offset >= span.count {
+		return nil, span.count, nil
+	}
+
+	count := min(size, span.count-offset)
+	data := make([]byte, count*membershipRecordSize)
+	_, err := m.file.ReadAt(data, int64(span.start+offset)*membershipRecordSize)
+	if err != nil {
+		return nil, 0, fmt.Errorf("zendesk-connector: failed to read temporary membership file: %w", err)
+	}
+
+	memberships := make([]membership, 0, count)
+	for record := data; len(record) >= membershipRecordSize; record = record[membershipRecordSize:] {
+		memberships = append(memberships, membership{
+			parentID: int64(binary.LittleEndian.Uint64(record)),
+			userID:   int64(binary.LittleEndian.Uint64(record[8:])),
+			id:       int64(binary.LittleEndian.Uint64(record[16:])),
+		})
+	}
+
+	return memberships, span.count, nil
+}
+
+// close removes the temporary file of the store, if any.
+func (m *membershipStore) close() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var err error
+	if m.file != nil {
+		err = m.file.Close()
+		m.file = nil
+		m.spans = nil
+	}
+	m.count = 0
+	m.parents = make(map[int64][]membership)
+
+	return err
+}
+
+// createSpillFile creates a temporary file readable only by the connector. The file is unlinked right away, so that
+// it is gone once closed even if the connector is killed. Where the system does not allow unlinking an open file,
+// nothing is spilled rather than leaving personal data behind.
+func createSpillFile(pattern string) (*os.File, error) {
+	file, err := os.CreateTemp("", pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	err = os.Remove(file.Name())
+	if err != nil {
+		// The file was not unlinked, so its name still belongs to it.
+		return nil, errors.Join(err, file.Close(), os.Remove(file.Name()))
+	}
+
+	return file, nil
+}
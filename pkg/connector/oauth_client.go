@@ -0,0 +1,70 @@
+package connector
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	"github.com/conductorone/baton-zendesk/pkg/client"
+)
+
+type oauthClientResourceType struct {
+	resourceType *v2.ResourceType
+	client       *client.ZendeskClient
+}
+
+func (o *oauthClientResourceType) ResourceType(_ context.Context) *v2.ResourceType {
+	return o.resourceType
+}
+
+// List returns all the OAuth clients of the account as app resources.
+func (o *oauthClientResourceType) List(ctx context.Context, parentId *v2.ResourceId, pToken *pagination.Token) ([]*v2.Resource, string, annotations.Annotations, error) {
+	var (
+		pageToken int
+		err       error
+		ret       []*v2.Resource
+	)
+	if pToken.Token != "" {
+		pageToken, err = strconv.Atoi(pToken.Token)
+		if err != nil {
+			return nil, "", nil, err
+		}
+	}
+
+	oauthClients, nextPageToken, err := o.client.ListOAuthClients(ctx, pageToken)
+	if err != nil {
+		return nil, "", nil, fmt.Errorf("zendesk-connector: failed to list oauth clients: %w", err)
+	}
+
+	for _, oauthClient := range oauthClients {
+		oauthClientCopy := oauthClient
+		res, err := getOAuthClientResource(&oauthClientCopy, resourceTypeOAuthClient)
+		if err != nil {
+			return nil, "", nil, err
+		}
+
+		ret = append(ret, res)
+	}
+
+	return ret, nextPageToken, nil, nil
+}
+
+// Entitlements always returns an empty slice for OAuth clients.
+func (o *oauthClientResourceType) Entitlements(_ context.Context, _ *v2.Resource, _ *pagination.Token) ([]*v2.Entitlement, string, annotations.Annotations, error) {
+	return nil, "", nil, nil
+}
+
+// Grants always returns an empty slice for OAuth clients.
+func (o *oauthClientResourceType) Grants(_ context.Context, _ *v2.Resource, _ *pagination.Token) ([]*v2.Grant, string, annotations.Annotations, error) {
+	return nil, "", nil, nil
+}
+
+func oauthClientBuilder(c *client.ZendeskClient) *oauthClientResourceType {
+	return &oauthClientResourceType{
+		resourceType: resourceTypeOAuthClient,
+		client:       c,
+	}
+}
@@ -0,0 +1,101 @@
+package connector
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	"github.com/conductorone/baton-zendesk/pkg/client"
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
+)
+
+type oauthTokenResourceType struct {
+	resourceType *v2.ResourceType
+	client       *client.ZendeskClient
+}
+
+func (o *oauthTokenResourceType) ResourceType(_ context.Context) *v2.ResourceType {
+	return o.resourceType
+}
+
+// List returns all the OAuth tokens issued on the account as secret resources.
+func (o *oauthTokenResourceType) List(ctx context.Context, parentId *v2.ResourceId, pToken *pagination.Token) ([]*v2.Resource, string, annotations.Annotations, error) {
+	var (
+		pageToken int
+		err       error
+		ret       []*v2.Resource
+	)
+	if pToken.Token != "" {
+		pageToken, err = strconv.Atoi(pToken.Token)
+		if err != nil {
+			return nil, "", nil, err
+		}
+	}
+
+	tokens, nextPageToken, err := o.client.ListOAuthTokens(ctx, pageToken)
+	if err != nil {
+		return nil, "", nil, fmt.Errorf("zendesk-connector: failed to list oauth tokens: %w", err)
+	}
+
+	for _, token := range tokens {
+		tokenCopy := token
+		res, err := getOAuthTokenResource(&tokenCopy, resourceTypeOAuthToken)
+		if err != nil {
+			return nil, "", nil, err
+		}
+
+		ret = append(ret, res)
+	}
+
+	return ret, nextPageToken, nil, nil
+}
+
+// Entitlements always returns an empty slice for OAuth tokens.
+func (o *oauthTokenResourceType) Entitlements(_ context.Context, _ *v2.Resource, _ *pagination.Token) ([]*v2.Entitlement, string, annotations.Annotations, error) {
+	return nil, "", nil, nil
+}
+
+// Grants always returns an empty slice for OAuth tokens.
+func (o *oauthTokenResourceType) Grants(_ context.Context, _ *v2.Resource, _ *pagination.Token) ([]*v2.Grant, string, annotations.Annotations, error) {
+	return nil, "", nil, nil
+}
+
+// Create is not supported, OAuth tokens are only issued through the OAuth flow.
+func (o *oauthTokenResourceType) Create(_ context.Context, _ *v2.Resource) (*v2.Resource, annotations.Annotations, error) {
+	return nil, nil, fmt.Errorf("zendesk-connector: oauth tokens cannot be created")
+}
+
+// Delete revokes an OAuth token.
+func (o *oauthTokenResourceType) Delete(ctx context.Context, resourceId *v2.ResourceId) (annotations.Annotations, error) {
+	l := ctxzap.Extract(ctx)
+	if resourceId.ResourceType != resourceTypeOAuthToken.Id {
+		return nil, fmt.Errorf("zendesk-connector: only oauth tokens can be revoked")
+	}
+
+	tokenID, err := strconv.ParseInt(resourceId.Resource, 10, 64)
+	if err != nil {
+		return nil, err
+	}
+
+	err = o.client.RevokeOAuthToken(ctx, tokenID)
+	if err != nil {
+		return nil, fmt.Errorf("zendesk-connector: failed to revoke oauth token: %w", err)
+	}
+
+	l.Warn("OAuth token has been revoked.",
+		zap.Int64("TokenID", tokenID),
+	)
+
+	return nil, nil
+}
+
+func oauthTokenBuilder(c *client.ZendeskClient) *oauthTokenResourceType {
+	return &oauthTokenResourceType{
+		resourceType: resourceTypeOAuthToken,
+		client:       c,
+	}
+}
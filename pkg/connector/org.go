@@ -2,7 +2,9 @@ package connector
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"slices"
 	"strconv"
 	"strings"
 
@@ -21,9 +23,36 @@ import (
 type orgResourceType struct {
 	resourceType *v2.ResourceType
 	client       *client.ZendeskClient
+	users        *userDirectory
+	members      *teamMemberFilter
 	orgs         map[string]struct{}
+	customFields *customFieldConfig
+	// orgQuery scopes organizations to those matching a Zendesk search query.
+	orgQuery string
+	prefetch *prefetcher
+	sweep    *membershipSweep
+	batch    *membershipBatcher
 }
 
+// orgUsersPage is a page of the members of an organization, along with the IDs of their memberships when they were
+// swept account-wide.
+type orgUsersPage struct {
+	users         []zendesk.User
+	membershipIDs map[int64]int64
+	nextPage      string
+}
+
+// orgPrefetchKey identifies the first page of members of an organization, for a page size.
+func orgPrefetchKey(orgID string, size int) string {
+	return fmt.Sprintf("org:%s:%d", orgID, size)
+}
+
+// The properties the orgs filter is searched by.
+const (
+	orgSearchName       = "name"
+	orgSearchExternalID = "external_id"
+)
+
 const (
 	orgRoleMember = "end-user"
 	orgRoleAdmin  = "admin"
@@ -43,11 +72,79 @@ func (o *orgResourceType) ResourceType(_ context.Context) *v2.ResourceType {
 // List returns all the organizations from the database as resource objects.
 func (o *orgResourceType) List(ctx context.Context, parentResourceID *v2.ResourceId, pToken *pagination.Token) ([]*v2.Resource, string, annotations.Annotations, error) {
 	var ret []*v2.Resource
-	_, page, err := parsePageToken(pToken.Token, &v2.ResourceId{ResourceType: resourceTypeOrg.Id})
+
+	// A sync starts by listing the first page of orgs, so memberships are swept or prefetched again for its grants.
+	if pToken.Token == "" {
+		if o.sweep != nil {
+			o.sweep.invalidateOrgs(ctx)
+		} else {
+			o.prefetch.reset()
+		}
+	}
+
+	orgs, nextPageToken, err := o.listOrgs(ctx, pToken)
 	if err != nil {
 		return nil, "", nil, err
 	}
 
+	for _, org := range orgs {
+		if !includesOrg(o.orgs, org) {
+			continue
+		}
+
+		orgFields, err := o.customFields.orgFields(ctx, o.client, org.OrganizationFields)
+		if err != nil {
+			return nil, "", nil, err
+		}
+
+		orgOptions := []rs.ResourceOption{
+			rs.WithParentResourceID(parentResourceID),
+			rs.WithAnnotation(
+				&v2.ExternalLink{Url: org.URL},
+				&v2.V1Identifier{Id: fmt.Sprintf("org:%d", org.ID)},
+				&v2.ChildResourceType{ResourceTypeId: resourceTypeTeam.Id},
+			),
+		}
+		orgOptions = append(orgOptions, externalIDOptions(org.ExternalID)...)
+		if len(orgFields) > 0 {
+			orgOptions = append(orgOptions, rs.WithDescription(fieldsDescription(orgFields)))
+		}
+
+		orgResource, err := rs.NewResource(
+			org.Name,
+			resourceTypeOrg,
+			org.ID,
+			orgOptions...,
+		)
+		if err != nil {
+			return nil, "", nil, err
+		}
+
+		ret = append(ret, orgResource)
+
+		if o.sweep == nil {
+			orgID, size := orgResource.Id, pToken.Size
+			o.prefetch.queue(orgPrefetchKey(orgID.Resource, size), func(ctx context.Context) (interface{}, error) {
+				return o.fetchUsersPage(ctx, orgID, 0, size)
+			})
+		}
+	}
+
+	return ret, nextPageToken, nil, nil
+}
+
+// listOrgs returns a page of organizations, fetched through the search export API when an org query or the orgs
+// filter is configured.
+func (o *orgResourceType) listOrgs(ctx context.Context, pToken *pagination.Token) ([]zendesk.Organization, string, error) {
+	if o.orgQuery != "" || len(o.orgs) > 0 {
+		return o.searchOrgs(ctx, pToken.Token)
+	}
+
+	bag, page, err := parsePageToken(pToken.Token, &v2.ResourceId{ResourceType: resourceTypeOrg.Id})
+	if err != nil {
+		return nil, "", err
+	}
+
 	opts := &zendesk.OrganizationListOptions{
 		PageOptions: zendesk.PageOptions{
 			Page:    page,
@@ -55,54 +152,89 @@ func (o *orgResourceType) List(ctx context.Context, parentResourceID *v2.Resourc
 		},
 	}
 
-	orgs, nextPageToken, err := o.client.ListOrganizations(ctx, opts)
+	orgs, nextPage, err := o.client.ListOrganizations(ctx, opts)
 	if err != nil {
-		return nil, "", nil, fmt.Errorf("zendesk-connector: failed to fetch org: %w", err)
+		return nil, "", fmt.Errorf("zendesk-connector: failed to fetch org: %w", err)
 	}
 
-	optsOrg := zendesk.UserListOptions{
-		Roles: []string{
-			"admin",
-		},
+	nextPageToken, err := bag.NextToken(nextPage)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return orgs, nextPageToken, nil
+}
+
+// searchOrgs returns a page of the organizations matching the org query. The orgs filter is searched by name and
+// then by external ID, since search terms naming different properties must all match, unless the org query already
+// searches either property.
+func (o *orgResourceType) searchOrgs(ctx context.Context, token string) ([]zendesk.Organization, string, error) {
+	if len(o.orgs) == 0 || queryNamesProperty(o.orgQuery, orgSearchName) || queryNamesProperty(o.orgQuery, orgSearchExternalID) {
+		orgs, nextPageToken, err := o.client.SearchOrganizations(ctx, o.orgQuery, token)
+		if err != nil {
+			return nil, "", fmt.Errorf("zendesk-connector: failed to search orgs: %w", err)
+		}
+
+		return orgs, nextPageToken, nil
 	}
 
-	users, _, err := o.client.GetUsers(ctx, &optsOrg)
+	bag := &pagination.Bag{}
+	err := bag.Unmarshal(token)
 	if err != nil {
-		return nil, "", nil, err
+		return nil, "", err
+	}
+	if bag.Current() == nil {
+		bag.Push(pagination.PageState{ResourceTypeID: resourceTypeOrg.Id, ResourceID: orgSearchExternalID})
+		bag.Push(pagination.PageState{ResourceTypeID: resourceTypeOrg.Id, ResourceID: orgSearchName})
 	}
 
-	for _, org := range orgs {
-		if _, ok := o.orgs[org.Name]; !ok && len(o.orgs) > 0 {
+	property := bag.ResourceID()
+	found, nextPage, err := o.client.SearchOrganizations(ctx, searchQuery(o.orgQuery, property, o.orgNames()), bag.PageToken())
+	if err != nil {
+		return nil, "", fmt.Errorf("zendesk-connector: failed to search orgs: %w", err)
+	}
+
+	orgs := make([]zendesk.Organization, 0, len(found))
+	for _, org := range found {
+		// Orgs matching by name were returned by the search by name already.
+		if _, ok := o.orgs[org.Name]; ok && property == orgSearchExternalID {
 			continue
 		}
+		orgs = append(orgs, org)
+	}
 
-		members := getOrganizationMembers(org.ID, users)
-		for _, member := range members {
-			if member.OrganizationID != org.ID {
-				continue
-			}
+	nextPageToken, err := bag.NextToken(nextPage)
+	if err != nil {
+		return nil, "", err
+	}
 
-			orgResource, err := rs.NewResource(
-				org.Name,
-				resourceTypeOrg,
-				org.ID,
-				rs.WithParentResourceID(parentResourceID),
-				rs.WithAnnotation(
-					&v2.ExternalLink{Url: org.URL},
-					&v2.V1Identifier{Id: fmt.Sprintf("org:%d", org.ID)},
-					&v2.ChildResourceType{ResourceTypeId: resourceTypeTeam.Id},
-				),
-			)
+	return orgs, nextPageToken, nil
+}
 
-			if err != nil {
-				return nil, "", nil, err
-			}
+// orgNames returns the names and external IDs of the orgs filter, sorted.
+func (o *orgResourceType) orgNames() []string {
+	names := make([]string, 0, len(o.orgs))
+	for name := range o.orgs {
+		names = append(names, name)
+	}
+	slices.Sort(names)
 
-			ret = append(ret, orgResource)
-		}
+	return names
+}
+
+// includesOrg reports whether an organization matches the orgs filter, by name or by external ID.
+func includesOrg(orgs map[string]struct{}, org zendesk.Organization) bool {
+	if len(orgs) == 0 {
+		return true
+	}
+	if _, ok := orgs[org.Name]; ok {
+		return true
+	}
+	if _, ok := orgs[org.ExternalID]; ok && org.ExternalID != "" {
+		return true
 	}
 
-	return ret, nextPageToken, nil, nil
+	return false
 }
 
 func (o *orgResourceType) Entitlements(_ context.Context, resource *v2.Resource, _ *pagination.Token) ([]*v2.Entitlement, string, annotations.Annotations, error) {
@@ -123,46 +255,138 @@ func (o *orgResourceType) Entitlements(_ context.Context, resource *v2.Resource,
 
 func (o *orgResourceType) Grants(ctx context.Context, resource *v2.Resource, pToken *pagination.Token) ([]*v2.Grant, string, annotations.Annotations, error) {
 	var rv []*v2.Grant
-	_, page, err := parsePageToken(pToken.Token, resource.Id)
+	bag, page, err := parsePageToken(pToken.Token, resource.Id)
 	if err != nil {
 		return nil, "", nil, err
 	}
 
-	opts := zendesk.UserListOptions{
-		PageOptions: zendesk.PageOptions{
-			Page:    page,
-			PerPage: pToken.Size,
-		},
+	var members *orgUsersPage
+	if o.sweep != nil {
+		members, err = o.sweptUsersPage(ctx, resource.Id, page)
+		if err != nil {
+			return nil, "", nil, err
+		}
+	} else if page == 0 {
+		if prefetched, ok := o.prefetch.take(ctx, orgPrefetchKey(resource.Id.Resource, pToken.Size)); ok {
+			members = prefetched.(*orgUsersPage)
+		}
 	}
-	users, nextPageToken, err := o.client.GetOrganizationUsers(ctx, resource.Id, &opts)
-	if err != nil {
-		return nil, "", nil, fmt.Errorf("zendesk-connector: failed to list org members: %w", err)
+	if members == nil {
+		members, err = o.fetchUsersPage(ctx, resource.Id, page, pToken.Size)
+		if err != nil {
+			return nil, "", nil, err
+		}
 	}
 
-	for _, user := range users {
-		ur, err := getUserResource(user, resourceTypeTeam)
+	for _, user := range members.users {
+		// End-users keep their end-user grant, while staff outside the configured team member roles are skipped.
+		if user.Role != orgRoleMember && !o.members.includes(&user) {
+			continue
+		}
+		gr, err := orgMembershipGrant(ctx, resource, user, members.membershipIDs[user.ID])
 		if err != nil {
 			return nil, "", nil, err
 		}
+		if gr != nil {
+			rv = append(rv, gr)
+		}
+	}
+
+	nextPageToken, err := bag.NextToken(members.nextPage)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	return rv, nextPageToken, nil, nil
+}
+
+// orgMembershipGrant returns the grant of a user's membership in an organization, which is named after the role
+// of the user, recording the ID of the membership when it is known. Users of unknown roles have no grant.
+func orgMembershipGrant(ctx context.Context, resource *v2.Resource, user zendesk.User, membershipID int64) (*v2.Grant, error) {
+	ur, err := getUserResource(user, resourceTypeTeam)
+	if err != nil {
+		return nil, err
+	}
 
-		roleName := strings.ToLower(user.Role)
-		switch roleName {
-		case orgRoleAdmin, orgRoleMember, orgRoleAgent:
-			rv = append(rv, grant.NewGrant(resource, roleName, ur.Id, grant.WithAnnotation(&v2.V1Identifier{
+	roleName := strings.ToLower(user.Role)
+	switch roleName {
+	case orgRoleAdmin, orgRoleMember, orgRoleAgent:
+		return grant.NewGrant(resource, roleName, ur.Id,
+			grant.WithAnnotation(&v2.V1Identifier{
 				Id: fmt.Sprintf("org-grant:%s:%d:%s", resource.Id.Resource, user.ID, roleName),
-			})))
-		default:
-			ctxzap.Extract(ctx).Warn("Unknown Zendesk Role Name",
-				zap.String("role_name", roleName),
-				zap.String("zendesk_username", user.Name),
-			)
+			}),
+			withMembershipID(membershipID),
+		), nil
+	default:
+		ctxzap.Extract(ctx).Warn("Unknown Zendesk Role Name",
+			zap.String("role_name", roleName),
+			zap.String("zendesk_username", user.Name),
+		)
+		return nil, nil
+	}
+}
+
+// sweptUsersPage returns a page of the members of an organization from the account-wide membership index.
+func (o *orgResourceType) sweptUsersPage(ctx context.Context, orgID *v2.ResourceId, offset int) (*orgUsersPage, error) {
+	id, err := strconv.ParseInt(orgID.Resource, 10, 64)
+	if err != nil {
+		return nil, err
+	}
+
+	idx, err := o.sweep.orgIndex(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	swept, users, nextPage, err := idx.page(id, offset)
+	idx.release(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	members := make([]zendesk.User, 0, len(swept))
+	membershipIDs := make(map[int64]int64, len(swept))
+	for _, m := range swept {
+		user, ok := users[m.userID]
+		if !ok {
+			user, err = o.users.get(ctx, m.userID)
+			if err != nil {
+				return nil, fmt.Errorf("zendesk-connector: failed to get org member %d: %w", m.userID, err)
+			}
 		}
+		members = append(members, user)
+		membershipIDs[m.userID] = m.id
 	}
 
-	return rv, nextPageToken, nil, nil
+	return &orgUsersPage{
+		users:         members,
+		membershipIDs: membershipIDs,
+		nextPage:      nextPage,
+	}, nil
 }
 
-func (o *orgResourceType) Grant(ctx context.Context, principal *v2.Resource, entitlement *v2.Entitlement) (annotations.Annotations, error) {
+func (o *orgResourceType) fetchUsersPage(ctx context.Context, orgID *v2.ResourceId, page int, size int) (*orgUsersPage, error) {
+	opts := zendesk.UserListOptions{
+		PageOptions: zendesk.PageOptions{
+			Page:    page,
+			PerPage: size,
+		},
+	}
+	users, nextPage, err := o.client.GetOrganizationUsers(ctx, orgID, &opts)
+	if err != nil {
+		return nil, fmt.Errorf("zendesk-connector: failed to list org members: %w", err)
+	}
+	o.users.add(users)
+
+	return &orgUsersPage{
+		users:    users,
+		nextPage: nextPage,
+	}, nil
+}
+
+// Grant adds a user to an organization, and returns the grant of the membership along with its ID,
+// so that revoking it deletes the membership directly.
+func (o *orgResourceType) Grant(ctx context.Context, principal *v2.Resource, entitlement *v2.Entitlement) ([]*v2.Grant, annotations.Annotations, error) {
 	l := ctxzap.Extract(ctx)
 	if principal.Id.ResourceType != resourceTypeTeam.Id {
 		l.Warn(
@@ -170,17 +394,61 @@ func (o *orgResourceType) Grant(ctx context.Context, principal *v2.Resource, ent
 			zap.String("principal_type", principal.Id.ResourceType),
 			zap.String("principal_id", principal.Id.Resource),
 		)
-		return nil, fmt.Errorf("zendesk-connector: only users can be granted organization membership")
+		return nil, nil, fmt.Errorf("zendesk-connector: only users can be granted organization membership")
 	}
 
-	userID, err := strconv.ParseInt(principal.Id.Resource, 10, 64)
+	userID, err := resolveUserID(ctx, o.client, principal)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	organizationID, err := strconv.ParseInt(entitlement.Resource.Id.Resource, 10, 64)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
+	}
+
+	user, err := o.users.get(ctx, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if o.batch != nil {
+		membershipID, err := o.batch.addOrgs.submit(ctx, organizationID, userID, 0)
+		if errors.Is(err, errMembershipExists) {
+			return orgAlreadyGranted(ctx, entitlement.Resource, user, membershipID)
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("zendesk-connector: failed to add user to an organization: %w", err)
+		}
+
+		o.users.invalidate(userID)
+		if o.sweep != nil {
+			o.sweep.addOrgMembership(membership{id: membershipID, parentID: organizationID, userID: userID})
+		}
+
+		l.Warn("Membership has been created.",
+			zap.Int64("ID", membershipID),
+			zap.Int64("UserID", userID),
+			zap.Int64("OganizationID", organizationID),
+		)
+
+		return orgGranted(ctx, entitlement.Resource, user, membershipID)
+	}
+
+	existingID, _, err := o.client.GetOrganizationMembershipByUser(ctx, zendesk.OrganizationMembershipListOptions{
+		OrganizationID: organizationID,
+		UserID:         userID,
+	})
+	if err == nil {
+		membershipID, err := strconv.ParseInt(existingID, 10, 64)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		return orgAlreadyGranted(ctx, entitlement.Resource, user, membershipID)
+	}
+	if !errors.Is(err, client.ErrMembershipNotFound) {
+		return nil, nil, err
 	}
 
 	organizationMembership := zendesk.OrganizationMembership{
@@ -189,7 +457,12 @@ func (o *orgResourceType) Grant(ctx context.Context, principal *v2.Resource, ent
 	}
 	oganizationMembership, err := o.client.CreateOrganizationMembership(ctx, organizationMembership)
 	if err != nil {
-		return nil, fmt.Errorf("zendesk-connector: failed to add user to an organization: %s", err.Error())
+		return nil, nil, fmt.Errorf("zendesk-connector: failed to add user to an organization: %w", err)
+	}
+
+	o.users.invalidate(userID)
+	if o.sweep != nil {
+		o.sweep.addOrgMembership(membership{id: oganizationMembership.ID, parentID: organizationID, userID: userID})
 	}
 
 	l.Warn("Membership has been created.",
@@ -199,7 +472,7 @@ func (o *orgResourceType) Grant(ctx context.Context, principal *v2.Resource, ent
 		zap.Time("CreatedAt", oganizationMembership.CreatedAt),
 	)
 
-	return nil, nil
+	return orgGranted(ctx, entitlement.Resource, user, oganizationMembership.ID)
 }
 
 func (o *orgResourceType) Revoke(ctx context.Context, grant *v2.Grant) (annotations.Annotations, error) {
@@ -217,7 +490,7 @@ func (o *orgResourceType) Revoke(ctx context.Context, grant *v2.Grant) (annotati
 		return nil, fmt.Errorf("zendesk-connector: only users can have organization membership revoked")
 	}
 
-	userID, err := strconv.ParseInt(principal.Id.Resource, 10, 64)
+	userID, err := resolveUserID(ctx, o.client, principal)
 	if err != nil {
 		return nil, err
 	}
@@ -227,23 +500,129 @@ func (o *orgResourceType) Revoke(ctx context.Context, grant *v2.Grant) (annotati
 		return nil, err
 	}
 
+	// Grants returned by Grant record the ID of the membership, which saves looking it up.
+	membershipID, hasMembershipID := grantMembershipID(grant)
+
+	switch {
+	case o.batch != nil:
+		_, err = o.batch.removeOrgs.submit(ctx, organizationID, userID, membershipID)
+		if errors.Is(err, client.ErrMembershipNotFound) {
+			return orgAlreadyRevoked(ctx, userID, organizationID), nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("zendesk-connector: failed to revoke organization: %w", err)
+		}
+
+		l.Warn("Membership has been revoked..",
+			zap.Int64("UserID", userID),
+			zap.Int64("OganizationID", organizationID),
+		)
+	case hasMembershipID:
+		err = o.client.DeleteOrganizationMembership(ctx, membershipID)
+		if client.IsNotFound(err) {
+			// The recorded membership is stale once the user was removed and added again, so the current one is looked up.
+			err = o.revokeOrgMembership(ctx, userID, organizationID)
+		} else if err == nil {
+			l.Warn("Membership has been revoked..",
+				zap.Int64("organizationMembershipID", membershipID),
+			)
+		}
+		if errors.Is(err, client.ErrMembershipNotFound) {
+			return orgAlreadyRevoked(ctx, userID, organizationID), nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("zendesk-connector: failed to revoke organization: %w", err)
+		}
+	default:
+		err = o.revokeOrgMembership(ctx, userID, organizationID)
+		if errors.Is(err, client.ErrMembershipNotFound) {
+			return orgAlreadyRevoked(ctx, userID, organizationID), nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("zendesk-connector: failed to revoke organization: %w", err)
+		}
+	}
+
+	o.users.invalidate(userID)
+	if o.sweep != nil {
+		o.sweep.removeOrgMembership(organizationID, userID)
+	}
+
+	return nil, nil
+}
+
+// revokeOrgMembership looks up the membership of a user in an organization and removes it. It returns
+// client.ErrMembershipNotFound if the user is not a member.
+func (o *orgResourceType) revokeOrgMembership(ctx context.Context, userID int64, organizationID int64) error {
 	organizationMembership := zendesk.OrganizationMembershipListOptions{
 		OrganizationID: organizationID,
 		UserID:         userID,
 	}
 	organizationMembershipID, err := o.client.RemoveOrganizationMembershipByID(ctx, organizationMembership)
+	if client.IsNotFound(err) {
+		return client.ErrMembershipNotFound
+	}
 	if err != nil {
-		return nil, fmt.Errorf("zendesk-connector: failed to revoke organization: %s", err.Error())
+		return err
 	}
 
-	l.Warn("Membership has been revoked..",
+	ctxzap.Extract(ctx).Warn("Membership has been revoked..",
 		zap.String("organizationMembershipID", organizationMembershipID),
 	)
 
-	return nil, nil
+	return nil
+}
+
+// orgGranted returns the grant of a membership created for a user.
+func orgGranted(ctx context.Context, resource *v2.Resource, user zendesk.User, membershipID int64) ([]*v2.Grant, annotations.Annotations, error) {
+	gr, err := orgMembershipGrant(ctx, resource, user, membershipID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("zendesk-connector: failed to create grant of organization %s: %w", resource.Id.Resource, err)
+	}
+	if gr == nil {
+		return nil, nil, nil
+	}
+
+	return []*v2.Grant{gr}, nil, nil
+}
+
+// orgAlreadyGranted reports a grant of a membership that already exists along with its grant,
+// so that retried grants succeed.
+func orgAlreadyGranted(ctx context.Context, resource *v2.Resource, user zendesk.User, membershipID int64) ([]*v2.Grant, annotations.Annotations, error) {
+	ctxzap.Extract(ctx).Warn("user is already a member of the organization",
+		zap.Int64("UserID", user.ID),
+		zap.String("OganizationID", resource.Id.Resource),
+	)
+
+	grants, _, err := orgGranted(ctx, resource, user, membershipID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return grants, annotations.New(&v2.GrantAlreadyExists{}), nil
+}
+
+// orgAlreadyRevoked reports a revoke of a membership that does not exist, so that retried revokes succeed.
+func orgAlreadyRevoked(ctx context.Context, userID int64, organizationID int64) annotations.Annotations {
+	ctxzap.Extract(ctx).Warn("user is not a member of the organization",
+		zap.Int64("UserID", userID),
+		zap.Int64("OganizationID", organizationID),
+	)
+
+	return annotations.New(&v2.GrantAlreadyRevoked{})
 }
 
-func orgBuilder(c *client.ZendeskClient, orgs []string) *orgResourceType {
+func orgBuilder(
+	c *client.ZendeskClient,
+	users *userDirectory,
+	members *teamMemberFilter,
+	orgs []string,
+	customFields *customFieldConfig,
+	orgQuery string,
+	prefetch *prefetcher,
+	sweep *membershipSweep,
+	batch *membershipBatcher,
+) *orgResourceType {
 	orgMap := make(map[string]struct{})
 
 	for _, o := range orgs {
@@ -254,5 +633,12 @@ func orgBuilder(c *client.ZendeskClient, orgs []string) *orgResourceType {
 		resourceType: resourceTypeOrg,
 		orgs:         orgMap,
 		client:       c,
+		users:        users,
+		members:      members,
+		customFields: customFields,
+		orgQuery:     orgQuery,
+		prefetch:     prefetch,
+		sweep:        sweep,
+		batch:        batch,
 	}
 }
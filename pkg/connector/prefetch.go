@@ -0,0 +1,207 @@
+package connector
+
+import (
+	"context"
+	"sync"
+)
+
+// rateLimitHeadroom is the share of the rate limit quota below which concurrency shrinks.
+const rateLimitHeadroom = 4
+
+// workerPool bounds the number of requests made in parallel. The bound shrinks, down to a single request,
+// as the rate limit quota reported by Zendesk runs low.
+type workerPool struct {
+	size  int
+	quota func() (int, int, bool)
+
+	mu     sync.Mutex
+	cond   *sync.Cond
+	active int
+}
+
+func newWorkerPool(size int, quota func() (int, int, bool)) *workerPool {
+	if size < 1 {
+		size = 1
+	}
+
+	p := &workerPool{
+		size:  size,
+		quota: quota,
+	}
+	p.cond = sync.NewCond(&p.mu)
+
+	return p
+}
+
+// limit returns the number of requests allowed in parallel. The full size is allowed while more than a quarter
+// of the quota remains, and it shrinks proportionally below that.
+func (p *workerPool) limit() int {
+	if p.quota == nil {
+		return p.size
+	}
+
+	remaining, limit, ok := p.quota()
+	if !ok || limit <= 0 {
+		return p.size
+	}
+
+	n := p.size * remaining * rateLimitHeadroom / limit
+	if n < 1 {
+		return 1
+	}
+	if n > p.size {
+		return p.size
+	}
+
+	return n
+}
+
+func (p *workerPool) acquire() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for p.active >= p.limit() {
+		p.cond.Wait()
+	}
+	p.active++
+}
+
+func (p *workerPool) release() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.active--
+	p.cond.Broadcast()
+}
+
+// prefetcher fetches the first page of grants of resources ahead of the Grants calls made for them one by one.
+// Resources are queued as they are listed, and each Grants call starts fetching the pages of the resources queued
+// after its own, as many as the worker pool runs in parallel. Prefetches belong to the sync that queued them:
+// they are cancelled when the next sync starts, when a Grants call waiting for one is cancelled,
+// or when the connector shuts down.
+type prefetcher struct {
+	pool *workerPool
+	// root lives as long as the connector, since the context of the Grants call starting a fetch ends with the call.
+	root context.Context
+
+	mu      sync.Mutex
+	ctx     context.Context
+	cancel  context.CancelFunc
+	order   []string
+	queued  map[string]*prefetch
+	started map[string]*prefetch
+}
+
+type prefetch struct {
+	index int
+	fetch func(ctx context.Context) (interface{}, error)
+	done  chan struct{}
+	value interface{}
+	err   error
+}
+
+func newPrefetcher(ctx context.Context, pool *workerPool) *prefetcher {
+	p := &prefetcher{
+		pool: pool,
+		root: ctx,
+	}
+	p.reset()
+
+	return p
+}
+
+// reset cancels the prefetches of the previous sync and drops its queue, as a new sync lists resources again.
+func (p *prefetcher) reset() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.cancel != nil {
+		p.cancel()
+	}
+	p.ctx, p.cancel = context.WithCancel(p.root)
+	p.order = nil
+	p.queued = make(map[string]*prefetch)
+	p.started = make(map[string]*prefetch)
+}
+
+// queue adds a listed resource, whose value is fetched with fetch once the Grants calls get close to it.
+func (p *prefetcher) queue(key string, fetch func(ctx context.Context) (interface{}, error)) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if _, ok := p.queued[key]; ok {
+		return
+	}
+	p.queued[key] = &prefetch{
+		index: len(p.order),
+		fetch: fetch,
+	}
+	p.order = append(p.order, key)
+}
+
+// take returns the value for key, after starting the fetches of the resources queued next and waiting for its own.
+// It returns false if key was not queued or the fetch failed, in which case the caller fetches the value itself.
+func (p *prefetcher) take(ctx context.Context, key string) (interface{}, bool) {
+	p.mu.Lock()
+	f, ok := p.queued[key]
+	if !ok {
+		p.mu.Unlock()
+		return nil, false
+	}
+	delete(p.queued, key)
+	delete(p.started, key)
+
+	// Values fetched for resources queued before this one were skipped by the Grants calls, so they are dropped.
+	for k, skipped := range p.started {
+		if skipped.index < f.index {
+			delete(p.started, k)
+			delete(p.queued, k)
+		}
+	}
+
+	p.startLocked(f)
+	for _, k := range p.order[f.index+1 : min(f.index+1+p.pool.size, len(p.order))] {
+		if next, ok := p.queued[k]; ok && next.done == nil {
+			p.startLocked(next)
+			p.started[k] = next
+		}
+	}
+	p.mu.Unlock()
+
+	select {
+	case <-f.done:
+	case <-ctx.Done():
+		// The sync is most likely cancelled, so the fetches it started are too.
+		p.mu.Lock()
+		p.cancel()
+		p.mu.Unlock()
+		return nil, false
+	}
+	if f.err != nil {
+		return nil, false
+	}
+
+	return f.value, true
+}
+
+// startLocked fetches the value of f in the background once a worker is available, unless it was started already.
+func (p *prefetcher) startLocked(f *prefetch) {
+	if f.done != nil {
+		return
+	}
+	f.done = make(chan struct{})
+
+	ctx := p.ctx
+	go func() {
+		defer close(f.done)
+
+		p.pool.acquire()
+		defer p.pool.release()
+
+		if err := ctx.Err(); err != nil {
+			f.err = err
+			return
+		}
+		f.value, f.err = f.fetch(ctx)
+	}()
+}
@@ -0,0 +1,131 @@
+package connector
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func TestWorkerPoolShrinksWithQuota(t *testing.T) {
+	remaining := 700
+	pool := newWorkerPool(8, func() (int, int, bool) { return remaining, 700, true })
+
+	for _, tc := range []struct {
+		remaining int
+		want      int
+	}{
+		{700, 8},
+		{175, 8},
+		{70, 3},
+		{5, 1},
+		{0, 1},
+	} {
+		remaining = tc.remaining
+		if got := pool.limit(); got != tc.want {
+			t.Errorf("limit with %d of 700 requests remaining is %d, want %d", tc.remaining, got, tc.want)
+		}
+	}
+}
+
+func TestPrefetcherBoundsConcurrency(t *testing.T) {
+	ctx := context.Background()
+	p := newPrefetcher(ctx, newWorkerPool(2, nil))
+
+	var (
+		active  atomic.Int32
+		maxSeen atomic.Int32
+		begun   = make(chan struct{}, 4)
+		release = make(chan struct{})
+	)
+	keys := []string{"a", "b", "c", "d"}
+	for _, key := range keys {
+		value := key
+		p.queue(key, func(context.Context) (interface{}, error) {
+			n := active.Add(1)
+			for {
+				seen := maxSeen.Load()
+				if n <= seen || maxSeen.CompareAndSwap(seen, n) {
+					break
+				}
+			}
+			begun <- struct{}{}
+			<-release
+			active.Add(-1)
+			return value, nil
+		})
+	}
+
+	// Taking a starts fetching b and c ahead of it. Two fetches start, and the other waits for them to complete.
+	taken := make(chan interface{})
+	go func() {
+		value, _ := p.take(ctx, "a")
+		taken <- value
+	}()
+	<-begun
+	<-begun
+	close(release)
+	if value := <-taken; value != "a" {
+		t.Errorf("took %v for a, want a", value)
+	}
+
+	for _, key := range keys[1:] {
+		value, ok := p.take(ctx, key)
+		if !ok || value != key {
+			t.Errorf("took %v (%t) for %s, want %s", value, ok, key, key)
+		}
+	}
+	if got := maxSeen.Load(); got > 2 {
+		t.Errorf("%d fetches ran in parallel, want at most 2", got)
+	}
+
+	if _, ok := p.take(ctx, "a"); ok {
+		t.Errorf("a prefetched value was taken twice")
+	}
+}
+
+func TestPrefetcherFetchesAheadWithinSync(t *testing.T) {
+	ctx := context.Background()
+	p := newPrefetcher(ctx, newWorkerPool(1, nil))
+
+	var fetched sync.Map
+	for _, key := range []string{"a", "b", "c"} {
+		value := key
+		p.queue(key, func(context.Context) (interface{}, error) {
+			fetched.Store(value, true)
+			return value, nil
+		})
+	}
+
+	// With a single worker, taking a fetches b ahead of it but not c.
+	if value, ok := p.take(ctx, "a"); !ok || value != "a" {
+		t.Errorf("took %v (%t) for a, want a", value, ok)
+	}
+	if _, ok := fetched.Load("c"); ok {
+		t.Errorf("c was fetched ahead of a with a single worker")
+	}
+
+	p = newPrefetcher(ctx, newWorkerPool(2, nil))
+	begun := make(chan struct{})
+	cancelled := make(chan struct{})
+	p.queue("a", func(context.Context) (interface{}, error) {
+		return "a", nil
+	})
+	p.queue("b", func(ctx context.Context) (interface{}, error) {
+		// b is still being fetched when the next sync starts.
+		close(begun)
+		<-ctx.Done()
+		close(cancelled)
+		return nil, ctx.Err()
+	})
+
+	if _, ok := p.take(ctx, "a"); !ok {
+		t.Errorf("a was not prefetched")
+	}
+	<-begun
+	p.reset()
+	<-cancelled
+	if _, ok := p.take(ctx, "b"); ok {
+		t.Errorf("b was taken after a new sync started")
+	}
+}
@@ -30,4 +30,53 @@ var (
 		},
 		Annotations: v1AnnotationsForResourceType("team_member"),
 	}
+	resourceTypeAPIToken = &v2.ResourceType{
+		Id:          "api_token",
+		DisplayName: "API Token",
+		Traits: []v2.ResourceType_Trait{
+			v2.ResourceType_TRAIT_SECRET,
+		},
+	}
+	resourceTypeOAuthClient = &v2.ResourceType{
+		Id:          "oauth_client",
+		DisplayName: "OAuth Client",
+		Traits: []v2.ResourceType_Trait{
+			v2.ResourceType_TRAIT_APP,
+		},
+	}
+	resourceTypeOAuthToken = &v2.ResourceType{
+		Id:          "oauth_token",
+		DisplayName: "OAuth Token",
+		Traits: []v2.ResourceType_Trait{
+			v2.ResourceType_TRAIT_SECRET,
+		},
+	}
+	resourceTypeAppInstallation = &v2.ResourceType{
+		Id:          "app_installation",
+		DisplayName: "App Installation",
+		Traits: []v2.ResourceType_Trait{
+			v2.ResourceType_TRAIT_APP,
+		},
+	}
+	resourceTypeWebhook = &v2.ResourceType{
+		Id:          "webhook",
+		DisplayName: "Webhook",
+		Traits: []v2.ResourceType_Trait{
+			v2.ResourceType_TRAIT_APP,
+		},
+	}
+	resourceTypeTarget = &v2.ResourceType{
+		Id:          "target",
+		DisplayName: "Target",
+		Traits: []v2.ResourceType_Trait{
+			v2.ResourceType_TRAIT_APP,
+		},
+	}
+	resourceTypeTag = &v2.ResourceType{
+		Id:          "tag",
+		DisplayName: "Tag",
+		Traits: []v2.ResourceType_Trait{
+			v2.ResourceType_TRAIT_GROUP,
+		},
+	}
 )
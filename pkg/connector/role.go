@@ -17,8 +17,13 @@ import (
 )
 
 type roleResourceType struct {
-	resourceType *v2.ResourceType
-	client       *client.ZendeskClient
+	resourceType         *v2.ResourceType
+	client               *client.ZendeskClient
+	users                *userDirectory
+	traits               *userTraitConfig
+	members              *teamMemberFilter
+	defaultRole          *userRole
+	signOutOnRoleRestore bool
 }
 
 func (r *roleResourceType) ResourceType(_ context.Context) *v2.ResourceType {
@@ -61,12 +66,12 @@ func (r *roleResourceType) Entitlements(ctx context.Context, resource *v2.Resour
 		}
 	}
 
-	users, nextPageToken, err := r.client.ListUsers(ctx, pageToken)
+	users, nextPageToken, err := r.users.listPage(ctx, pageToken)
 	if err != nil {
 		return nil, "", nil, err
 	}
 
-	for supportRole := range getUserSupportRoles(users) {
+	for supportRole := range getUserSupportRoles(users, r.members) {
 		permissionOptions := PopulateOptions(resource.DisplayName, supportRole, resource.Id.Resource)
 		permissionEn := ent.NewPermissionEntitlement(resource, supportRole, permissionOptions...)
 		rv = append(rv, permissionEn)
@@ -90,14 +95,14 @@ func (r *roleResourceType) Grants(ctx context.Context, resource *v2.Resource, to
 		}
 	}
 
-	users, nextPageToken, err := r.client.ListUsers(ctx, pageToken)
+	users, nextPageToken, err := r.users.listPage(ctx, pageToken)
 	if err != nil {
 		return nil, "", nil, err
 	}
 
 	for _, user := range users {
 		userCopy := user
-		if !isValidTeamMember(&userCopy) {
+		if !r.members.includes(&userCopy) {
 			continue
 		}
 
@@ -110,20 +115,19 @@ func (r *roleResourceType) Grants(ctx context.Context, resource *v2.Resource, to
 			continue
 		}
 
-		ur, err := getUserRoleResource(&userCopy, resourceTypeTeam)
+		grants, err := r.roleGrants(resource, &userCopy)
 		if err != nil {
-			return nil, "", nil, fmt.Errorf("error creating team_member resource for role %s: %w", resource.Id.Resource, err)
+			return nil, "", nil, err
 		}
-
-		gr := grant.NewGrant(resource, user.Role, ur.Id)
-		tr := grant.NewGrant(ur, user.Role, resource.Id)
-		rv = append(rv, gr, tr)
+		rv = append(rv, grants...)
 	}
 
 	return rv, nextPageToken, nil, nil
 }
 
-func (r *roleResourceType) Grant(ctx context.Context, principal *v2.Resource, entitlement *v2.Entitlement) (annotations.Annotations, error) {
+// Grant assigns the custom role to a team member, recording its current role in a user tag,
+// and returns the grants of the role.
+func (r *roleResourceType) Grant(ctx context.Context, principal *v2.Resource, entitlement *v2.Entitlement) ([]*v2.Grant, annotations.Annotations, error) {
 	l := ctxzap.Extract(ctx)
 
 	if principal.Id.ResourceType != resourceTypeTeam.Id {
@@ -132,17 +136,17 @@ func (r *roleResourceType) Grant(ctx context.Context, principal *v2.Resource, en
 			zap.String("principal_type", principal.Id.ResourceType),
 			zap.String("principal_id", principal.Id.Resource),
 		)
-		return nil, fmt.Errorf("baton-zendesk: only team members can be granted role membership")
+		return nil, nil, fmt.Errorf("baton-zendesk: only team members can be granted role membership")
 	}
 
-	userID, err := strconv.ParseInt(principal.Id.Resource, 10, 64)
+	userID, err := resolveUserID(ctx, r.client, principal)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	user, err := r.client.GetUser(ctx, userID)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	if user.Role == "end-user" {
@@ -150,39 +154,178 @@ func (r *roleResourceType) Grant(ctx context.Context, principal *v2.Resource, en
 			zap.Int64("user", user.ID),
 			zap.String("user.Role", user.Role),
 		)
-		return nil, fmt.Errorf("user must be a team member")
+		return nil, nil, fmt.Errorf("user must be a team member")
 	}
 
 	roleID, err := strconv.ParseInt(entitlement.Resource.Id.Resource, 10, 64)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
-	roleMembershipOptions := zendesk.CustomRole{
-		Name: fmt.Sprintf("Custom Role %d ", roleID),
+	if user.CustomRoleID == roleID {
+		l.Warn("team member already has the role",
+			zap.Int64("UserID", user.ID),
+			zap.Int64("CustomRoleID", roleID),
+		)
+		grants, err := r.roleGrants(entitlement.Resource, &user)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		return grants, annotations.New(&v2.GrantAlreadyExists{}), nil
 	}
-	membership, err := r.client.CreateCustomRoleMembership(ctx, roleMembershipOptions)
+
+	// The role the team member had before the first elevation is kept, so that revoking restores it. It is recorded
+	// through the tags endpoints, so that tags changed elsewhere in the meantime are not overwritten.
+	var previousRoleTag string
+	if previous, _ := findPreviousRole(user.Tags); previous == nil {
+		current := &userRole{role: user.Role, customRoleID: user.CustomRoleID}
+		previousRoleTag = current.tag()
+
+		err = r.client.AddUserTags(ctx, userID, []string{previousRoleTag})
+		if err != nil {
+			return nil, nil, fmt.Errorf("zendesk-connector: failed to record previous role of team member: %w", err)
+		}
+	}
+
+	updated, err := r.client.UpdateUserRole(ctx, userID, client.UserRoleUpdate{
+		CustomRoleID: &roleID,
+	})
 	if err != nil {
-		return nil, fmt.Errorf("zendesk-connector: failed to add team member to a group: %s", err.Error())
+		if previousRoleTag != "" {
+			rollbackErr := r.client.RemoveUserTags(ctx, userID, []string{previousRoleTag})
+			if rollbackErr != nil {
+				l.Error("zendesk-connector: failed to remove previous role tag after failed role assignment",
+					zap.Int64("UserID", userID),
+					zap.String("Tag", previousRoleTag),
+					zap.Error(rollbackErr),
+				)
+			}
+		}
+		return nil, nil, fmt.Errorf("zendesk-connector: failed to assign role to team member: %w", err)
 	}
+	r.users.invalidate(userID)
 
-	l.Warn("Role Membership has been created.",
-		zap.Int64("ID", membership.ID),
-		zap.String("Name", membership.Name),
-		zap.String("Configuration", fmt.Sprintf("%v", membership.Configuration)),
-		zap.Time("CreatedAt", membership.CreatedAt),
+	l.Warn("Role has been assigned.",
+		zap.Int64("UserID", updated.ID),
+		zap.String("Role", updated.Role),
+		zap.Int64("CustomRoleID", updated.CustomRoleID),
 	)
 
-	return nil, nil
+	grants, err := r.roleGrants(entitlement.Resource, &updated)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return grants, nil, nil
 }
 
+// roleGrants returns the grants of a custom role held by a team member, as Grants lists them.
+func (r *roleResourceType) roleGrants(resource *v2.Resource, user *zendesk.User) ([]*v2.Grant, error) {
+	ur, err := getUserRoleResource(user, r.traits, resourceTypeTeam)
+	if err != nil {
+		return nil, fmt.Errorf("error creating team_member resource for role %s: %w", resource.Id.Resource, err)
+	}
+
+	return []*v2.Grant{
+		grant.NewGrant(resource, user.Role, ur.Id),
+		grant.NewGrant(ur, user.Role, resource.Id),
+	}, nil
+}
+
+// Revoke restores the role a team member had before the custom role was granted,
+// falling back to the configured default role when none was recorded. With sign out on role restore,
+// the team member is also signed out of every session.
 func (r *roleResourceType) Revoke(ctx context.Context, grant *v2.Grant) (annotations.Annotations, error) {
+	l := ctxzap.Extract(ctx)
+
+	entitlement := grant.Entitlement
+	principal := grant.Principal
+
+	if principal.Id.ResourceType != resourceTypeTeam.Id {
+		return nil, fmt.Errorf("zendesk-connector: only team members can have role membership revoked")
+	}
+
+	userID, err := resolveUserID(ctx, r.client, principal)
+	if err != nil {
+		return nil, err
+	}
+
+	roleID, err := strconv.ParseInt(entitlement.Resource.Id.Resource, 10, 64)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := r.client.GetUser(ctx, userID)
+	if client.IsNotFound(err) {
+		// A deleted team member holds no role anymore.
+		return annotations.New(&v2.GrantAlreadyRevoked{}), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if user.CustomRoleID != roleID {
+		l.Warn("team member does not have the role",
+			zap.Int64("UserID", user.ID),
+			zap.Int64("CustomRoleID", roleID),
+		)
+		return annotations.New(&v2.GrantAlreadyRevoked{}), nil
+	}
+
+	previous, tag := findPreviousRole(user.Tags)
+	if previous == nil {
+		previous = r.defaultRole
+	}
+	if previous == nil {
+		return nil, fmt.Errorf("zendesk-connector: no previous role is recorded for team member %d and no default role is configured", userID)
+	}
+
+	// A previous role without a custom role clears the custom role, which is sent as null.
+	update := client.UserRoleUpdate{
+		Role: previous.role,
+	}
+	if previous.customRoleID != 0 {
+		update.CustomRoleID = &previous.customRoleID
+	}
+
+	updated, err := r.client.UpdateUserRole(ctx, userID, update)
+	if err != nil {
+		return nil, fmt.Errorf("zendesk-connector: failed to restore role of team member: %w", err)
+	}
+	r.users.invalidate(userID)
+
+	if tag != "" {
+		err = r.client.RemoveUserTags(ctx, userID, []string{tag})
+		if err != nil {
+			return nil, fmt.Errorf("zendesk-connector: failed to remove previous role tag of team member: %w", err)
+		}
+	}
+
+	l.Warn("Role has been restored.",
+		zap.Int64("UserID", updated.ID),
+		zap.String("Role", updated.Role),
+		zap.Int64("CustomRoleID", updated.CustomRoleID),
+	)
+
+	if r.signOutOnRoleRestore {
+		err = r.client.DeleteUserSessions(ctx, userID)
+		if err != nil {
+			return nil, fmt.Errorf("zendesk-connector: failed to sign out team member: %w", err)
+		}
+	}
+
 	return nil, nil
 }
 
-func roleBuilder(c *client.ZendeskClient) *roleResourceType {
+func roleBuilder(c *client.ZendeskClient, users *userDirectory, traits *userTraitConfig, members *teamMemberFilter, defaultRole *userRole, signOutOnRoleRestore bool) *roleResourceType {
 	return &roleResourceType{
-		resourceType: resourceTypeRole,
-		client:       c,
+		resourceType:         resourceTypeRole,
+		client:               c,
+		users:                users,
+		traits:               traits,
+		members:              members,
+		defaultRole:          defaultRole,
+		signOutOnRoleRestore: signOutOnRoleRestore,
 	}
 }
@@ -0,0 +1,69 @@
+package connector
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// previousRoleTagPrefix marks the user tag recording the role a team member had before being granted a custom role.
+const previousRoleTagPrefix = "baton_previous_role_"
+
+// userRole is a Zendesk role together with the custom role that narrows it, if any.
+type userRole struct {
+	role         string
+	customRoleID int64
+}
+
+// parseUserRole parses a role given as <role> or <role>:<custom role id>.
+func parseUserRole(value string) (*userRole, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	role, customRoleID, found := strings.Cut(value, ":")
+	switch role {
+	case "agent", "admin":
+	default:
+		return nil, fmt.Errorf("zendesk-connector: invalid role %q: role must be agent or admin", value)
+	}
+
+	r := &userRole{role: role}
+	if found {
+		id, err := strconv.ParseInt(customRoleID, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("zendesk-connector: invalid custom role id in %q: %w", value, err)
+		}
+		r.customRoleID = id
+	}
+
+	return r, nil
+}
+
+// tag returns the user tag recording the role.
+func (r *userRole) tag() string {
+	return fmt.Sprintf("%s%s_%d", previousRoleTagPrefix, r.role, r.customRoleID)
+}
+
+// findPreviousRole returns the recorded previous role of a user and the tag it was recorded in.
+func findPreviousRole(tags []string) (*userRole, string) {
+	for _, tag := range tags {
+		value, ok := strings.CutPrefix(tag, previousRoleTagPrefix)
+		if !ok {
+			continue
+		}
+
+		role, customRoleID, found := strings.Cut(value, "_")
+		if !found {
+			continue
+		}
+		id, err := strconv.ParseInt(customRoleID, 10, 64)
+		if err != nil {
+			continue
+		}
+
+		return &userRole{role: role, customRoleID: id}, tag
+	}
+
+	return nil, ""
+}
@@ -0,0 +1,355 @@
+package connector
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	ent "github.com/conductorone/baton-sdk/pkg/types/entitlement"
+	"github.com/conductorone/baton-sdk/pkg/types/grant"
+	"github.com/conductorone/baton-zendesk/pkg/client"
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"github.com/nukosuke/go-zendesk/zendesk"
+	"go.uber.org/zap"
+)
+
+const tagMemberEntitlement = "member"
+
+// tagResourceType syncs user and organization tags as groups, since triggers, views and Guide segments
+// grant access based on them. Only tags matching one of the configured prefixes are synced.
+type tagResourceType struct {
+	resourceType *v2.ResourceType
+	client       *client.ZendeskClient
+	users        *userDirectory
+	members      *teamMemberFilter
+	prefixes     []string
+	includeOrgs  bool
+	orgs         map[string]struct{}
+	orgQuery     string
+}
+
+func (t *tagResourceType) ResourceType(_ context.Context) *v2.ResourceType {
+	return t.resourceType
+}
+
+func (t *tagResourceType) matchesPrefix(tag string) bool {
+	for _, prefix := range t.prefixes {
+		if strings.HasPrefix(tag, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// searchTeamMembers returns a page of the team members matching a search query. The search is limited to the
+// team member roles, so that end-users are not read at all.
+func (t *tagResourceType) searchTeamMembers(ctx context.Context, query string, cursor string) ([]zendesk.User, string, error) {
+	found, nextCursor, err := t.client.SearchUsers(ctx, searchQuery(query, "role", t.members.roleNames()), cursor)
+	if err != nil {
+		return nil, "", err
+	}
+
+	users := make([]zendesk.User, 0, len(found))
+	for _, user := range found {
+		if t.members.includes(&user) {
+			users = append(users, user)
+		}
+	}
+
+	return users, nextCursor, nil
+}
+
+// searchOrgs returns a page of the synced organizations carrying a tag matching the tags search term. The org query
+// is searched along with the term, unless it has tags terms of its own, which Zendesk would match instead of the
+// term rather than as well. The tags are then matched on the results.
+func (t *tagResourceType) searchOrgs(ctx context.Context, term string, hasTag func(string) bool, cursor string) ([]zendesk.Organization, string, error) {
+	query := strings.TrimSpace(t.orgQuery + " " + term)
+	if queryNamesProperty(t.orgQuery, "tags") {
+		query = t.orgQuery
+	}
+
+	found, nextCursor, err := t.client.SearchOrganizations(ctx, query, cursor)
+	if err != nil {
+		return nil, "", err
+	}
+
+	orgs := make([]zendesk.Organization, 0, len(found))
+	for _, org := range found {
+		if includesOrg(t.orgs, org) && slices.ContainsFunc(org.Tags, hasTag) {
+			orgs = append(orgs, org)
+		}
+	}
+
+	return orgs, nextCursor, nil
+}
+
+// List discovers the tags by searching for the team members, and the synced organizations when they are included,
+// tagged with one of the prefixes. The tags API only lists the most popular ticket tags, so it can not be used.
+// Each page lists the tags found on a page of search results, so a tag carried on several pages is listed again.
+func (t *tagResourceType) List(ctx context.Context, parentId *v2.ResourceId, pToken *pagination.Token) ([]*v2.Resource, string, annotations.Annotations, error) {
+	bag := &pagination.Bag{}
+	err := bag.Unmarshal(pToken.Token)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	if bag.Current() == nil {
+		for i := len(t.prefixes) - 1; i >= 0; i-- {
+			if t.includeOrgs {
+				bag.Push(pagination.PageState{ResourceTypeID: resourceTypeOrg.Id, ResourceID: t.prefixes[i]})
+			}
+			bag.Push(pagination.PageState{ResourceTypeID: resourceTypeTeam.Id, ResourceID: t.prefixes[i]})
+		}
+	}
+	if bag.Current() == nil {
+		return nil, "", nil, nil
+	}
+
+	prefix := bag.ResourceID()
+	query := fmt.Sprintf("tags:%s*", prefix)
+
+	var (
+		found      []string
+		nextCursor string
+	)
+	switch bag.ResourceTypeID() {
+	case resourceTypeTeam.Id:
+		users, cursor, err := t.searchTeamMembers(ctx, query, bag.PageToken())
+		if err != nil {
+			return nil, "", nil, fmt.Errorf("zendesk-connector: failed to search users tagged %s*: %w", prefix, err)
+		}
+		nextCursor = cursor
+
+		for _, user := range users {
+			found = append(found, user.Tags...)
+		}
+	case resourceTypeOrg.Id:
+		orgs, cursor, err := t.searchOrgs(ctx, query, t.matchesPrefix, bag.PageToken())
+		if err != nil {
+			return nil, "", nil, fmt.Errorf("zendesk-connector: failed to search organizations tagged %s*: %w", prefix, err)
+		}
+		nextCursor = cursor
+
+		for _, org := range orgs {
+			found = append(found, org.Tags...)
+		}
+	default:
+		return nil, "", nil, fmt.Errorf("zendesk-connector: unexpected resource type in page token: %s", bag.ResourceTypeID())
+	}
+
+	found = slices.DeleteFunc(found, func(tag string) bool { return !t.matchesPrefix(tag) })
+	slices.Sort(found)
+	found = slices.Compact(found)
+
+	ret := make([]*v2.Resource, 0, len(found))
+	for _, name := range found {
+		res, err := getTagResource(&client.Tag{Name: name}, resourceTypeTag)
+		if err != nil {
+			return nil, "", nil, err
+		}
+
+		ret = append(ret, res)
+	}
+
+	nextPageToken, err := bag.NextToken(nextCursor)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	return ret, nextPageToken, nil, nil
+}
+
+func (t *tagResourceType) Entitlements(_ context.Context, resource *v2.Resource, _ *pagination.Token) ([]*v2.Entitlement, string, annotations.Annotations, error) {
+	grantableTo := []*v2.ResourceType{resourceTypeTeam}
+	if t.includeOrgs {
+		grantableTo = append(grantableTo, resourceTypeOrg)
+	}
+
+	return []*v2.Entitlement{
+		ent.NewAssignmentEntitlement(resource, tagMemberEntitlement,
+			ent.WithDisplayName(fmt.Sprintf("%s Tag Member", resource.DisplayName)),
+			ent.WithDescription(fmt.Sprintf("Tagged with %s in Zendesk", resource.DisplayName)),
+			ent.WithGrantableTo(grantableTo...),
+		),
+	}, "", nil, nil
+}
+
+// Grants returns the team members carrying the tag, followed by the synced organizations carrying it when orgs are
+// included.
+func (t *tagResourceType) Grants(ctx context.Context, resource *v2.Resource, pToken *pagination.Token) ([]*v2.Grant, string, annotations.Annotations, error) {
+	var rv []*v2.Grant
+
+	bag := &pagination.Bag{}
+	err := bag.Unmarshal(pToken.Token)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	if bag.Current() == nil {
+		if t.includeOrgs {
+			bag.Push(pagination.PageState{ResourceTypeID: resourceTypeOrg.Id})
+		}
+		bag.Push(pagination.PageState{ResourceTypeID: resourceTypeTeam.Id})
+	}
+
+	query := fmt.Sprintf("tags:%s", resource.Id.Resource)
+
+	var nextCursor string
+	switch bag.ResourceTypeID() {
+	case resourceTypeTeam.Id:
+		users, cursor, err := t.searchTeamMembers(ctx, query, bag.PageToken())
+		if err != nil {
+			return nil, "", nil, fmt.Errorf("zendesk-connector: failed to search users tagged %s: %w", resource.Id.Resource, err)
+		}
+		nextCursor = cursor
+
+		for _, user := range users {
+			ur, err := getUserResource(user, resourceTypeTeam)
+			if err != nil {
+				return nil, "", nil, err
+			}
+			rv = append(rv, grant.NewGrant(resource, tagMemberEntitlement, ur.Id))
+		}
+	case resourceTypeOrg.Id:
+		tag := resource.Id.Resource
+		orgs, cursor, err := t.searchOrgs(ctx, query, func(name string) bool { return name == tag }, bag.PageToken())
+		if err != nil {
+			return nil, "", nil, fmt.Errorf("zendesk-connector: failed to search organizations tagged %s: %w", resource.Id.Resource, err)
+		}
+		nextCursor = cursor
+
+		for _, org := range orgs {
+			orgID := &v2.ResourceId{
+				ResourceType: resourceTypeOrg.Id,
+				Resource:     strconv.FormatInt(org.ID, 10),
+			}
+			rv = append(rv, grant.NewGrant(resource, tagMemberEntitlement, orgID))
+		}
+	default:
+		return nil, "", nil, fmt.Errorf("zendesk-connector: unexpected resource type in page token: %s", bag.ResourceTypeID())
+	}
+
+	nextPageToken, err := bag.NextToken(nextCursor)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	return rv, nextPageToken, nil, nil
+}
+
+// Grant adds the tag to a user or an organization.
+func (t *tagResourceType) Grant(ctx context.Context, principal *v2.Resource, entitlement *v2.Entitlement) (annotations.Annotations, error) {
+	l := ctxzap.Extract(ctx)
+	tag := entitlement.Resource.Id.Resource
+
+	switch principal.Id.ResourceType {
+	case resourceTypeTeam.Id:
+		userID, err := resolveUserID(ctx, t.client, principal)
+		if err != nil {
+			return nil, err
+		}
+
+		err = t.client.AddUserTags(ctx, userID, []string{tag})
+		if err != nil {
+			return nil, fmt.Errorf("zendesk-connector: failed to tag user: %w", err)
+		}
+		t.users.invalidate(userID)
+
+		l.Warn("Tag has been added.",
+			zap.String("Tag", tag),
+			zap.Int64("UserID", userID),
+		)
+	case resourceTypeOrg.Id:
+		if !t.includeOrgs {
+			return nil, fmt.Errorf("zendesk-connector: organization tags are not synced")
+		}
+
+		orgID, err := strconv.ParseInt(principal.Id.Resource, 10, 64)
+		if err != nil {
+			return nil, err
+		}
+
+		err = t.client.AddOrganizationTags(ctx, orgID, []string{tag})
+		if err != nil {
+			return nil, fmt.Errorf("zendesk-connector: failed to tag organization: %w", err)
+		}
+
+		l.Warn("Tag has been added.",
+			zap.String("Tag", tag),
+			zap.Int64("OrganizationID", orgID),
+		)
+	default:
+		return nil, fmt.Errorf("zendesk-connector: only users and organizations can be tagged")
+	}
+
+	return nil, nil
+}
+
+// Revoke removes the tag from a user or an organization.
+func (t *tagResourceType) Revoke(ctx context.Context, grant *v2.Grant) (annotations.Annotations, error) {
+	l := ctxzap.Extract(ctx)
+	tag := grant.Entitlement.Resource.Id.Resource
+	principal := grant.Principal
+
+	switch principal.Id.ResourceType {
+	case resourceTypeTeam.Id:
+		userID, err := resolveUserID(ctx, t.client, principal)
+		if err != nil {
+			return nil, err
+		}
+
+		err = t.client.RemoveUserTags(ctx, userID, []string{tag})
+		if err != nil {
+			return nil, fmt.Errorf("zendesk-connector: failed to remove tag from user: %w", err)
+		}
+		t.users.invalidate(userID)
+
+		l.Warn("Tag has been removed.",
+			zap.String("Tag", tag),
+			zap.Int64("UserID", userID),
+		)
+	case resourceTypeOrg.Id:
+		orgID, err := strconv.ParseInt(principal.Id.Resource, 10, 64)
+		if err != nil {
+			return nil, err
+		}
+
+		err = t.client.RemoveOrganizationTags(ctx, orgID, []string{tag})
+		if err != nil {
+			return nil, fmt.Errorf("zendesk-connector: failed to remove tag from organization: %w", err)
+		}
+
+		l.Warn("Tag has been removed.",
+			zap.String("Tag", tag),
+			zap.Int64("OrganizationID", orgID),
+		)
+	default:
+		return nil, fmt.Errorf("zendesk-connector: only users and organizations can have tags removed")
+	}
+
+	return nil, nil
+}
+
+func tagBuilder(c *client.ZendeskClient, users *userDirectory, members *teamMemberFilter, prefixes []string, includeOrgs bool, orgs []string, orgQuery string) *tagResourceType {
+	orgMap := make(map[string]struct{}, len(orgs))
+	for _, o := range orgs {
+		orgMap[o] = struct{}{}
+	}
+
+	return &tagResourceType{
+		resourceType: resourceTypeTag,
+		client:       c,
+		users:        users,
+		members:      members,
+		prefixes:     prefixes,
+		includeOrgs:  includeOrgs,
+		orgs:         orgMap,
+		orgQuery:     orgQuery,
+	}
+}
@@ -0,0 +1,70 @@
+package connector
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	"github.com/conductorone/baton-zendesk/pkg/client"
+)
+
+type targetResourceType struct {
+	resourceType *v2.ResourceType
+	client       *client.ZendeskClient
+}
+
+func (t *targetResourceType) ResourceType(_ context.Context) *v2.ResourceType {
+	return t.resourceType
+}
+
+// List returns all the legacy targets of the account as app resources.
+func (t *targetResourceType) List(ctx context.Context, parentId *v2.ResourceId, pToken *pagination.Token) ([]*v2.Resource, string, annotations.Annotations, error) {
+	var (
+		pageToken int
+		err       error
+		ret       []*v2.Resource
+	)
+	if pToken.Token != "" {
+		pageToken, err = strconv.Atoi(pToken.Token)
+		if err != nil {
+			return nil, "", nil, err
+		}
+	}
+
+	targets, nextPageToken, err := t.client.ListTargets(ctx, pageToken)
+	if err != nil {
+		return nil, "", nil, fmt.Errorf("zendesk-connector: failed to list targets: %w", err)
+	}
+
+	for _, target := range targets {
+		targetCopy := target
+		res, err := getTargetResource(&targetCopy, resourceTypeTarget)
+		if err != nil {
+			return nil, "", nil, err
+		}
+
+		ret = append(ret, res)
+	}
+
+	return ret, nextPageToken, nil, nil
+}
+
+// Entitlements always returns an empty slice for targets.
+func (t *targetResourceType) Entitlements(_ context.Context, _ *v2.Resource, _ *pagination.Token) ([]*v2.Entitlement, string, annotations.Annotations, error) {
+	return nil, "", nil, nil
+}
+
+// Grants always returns an empty slice for targets.
+func (t *targetResourceType) Grants(_ context.Context, _ *v2.Resource, _ *pagination.Token) ([]*v2.Grant, string, annotations.Annotations, error) {
+	return nil, "", nil, nil
+}
+
+func targetBuilder(c *client.ZendeskClient) *targetResourceType {
+	return &targetResourceType{
+		resourceType: resourceTypeTarget,
+		client:       c,
+	}
+}
@@ -3,12 +3,18 @@ package connector
 import (
 	"context"
 	"fmt"
+	"maps"
 	"strconv"
 
 	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
 	"github.com/conductorone/baton-sdk/pkg/annotations"
 	"github.com/conductorone/baton-sdk/pkg/pagination"
 	ent "github.com/conductorone/baton-sdk/pkg/types/entitlement"
+	"github.com/conductorone/baton-sdk/pkg/types/grant"
+	rs "github.com/conductorone/baton-sdk/pkg/types/resource"
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"github.com/nukosuke/go-zendesk/zendesk"
+	"go.uber.org/zap"
 
 	"github.com/conductorone/baton-zendesk/pkg/client"
 )
@@ -21,6 +27,11 @@ const (
 	teamRoleLegacyAgent = "legacy agent"
 	teamRoleLightAgent  = "light agent"
 	teamRoleCustomRoles = "custom roles"
+
+	// teamSessionEntitlement is held by team members that have at least one active session.
+	// Revoking it signs the team member out of every session.
+	teamSessionEntitlement = "session"
+	profileSessionCount    = "session_count"
 )
 
 var teamAccessLevels = []string{
@@ -36,6 +47,14 @@ var teamAccessLevels = []string{
 type teamResourceType struct {
 	resourceType *v2.ResourceType
 	client       *client.ZendeskClient
+	users        *userDirectory
+	traits       *userTraitConfig
+	members      *teamMemberFilter
+	customFields *customFieldConfig
+	// userQuery scopes team members to the users matching a Zendesk search query.
+	userQuery string
+	// syncSessions lists the sessions of every team member, one request each, to grant the session entitlement.
+	syncSessions bool
 }
 
 func (t *teamResourceType) ResourceType(_ context.Context) *v2.ResourceType {
@@ -55,33 +74,56 @@ func (t *teamResourceType) Entitlements(ctx context.Context, resource *v2.Resour
 		))
 	}
 
+	rv = append(rv, ent.NewPermissionEntitlement(resource, teamSessionEntitlement,
+		ent.WithDisplayName(fmt.Sprintf("%s Team Member Session", resource.DisplayName)),
+		ent.WithDescription(fmt.Sprintf("Active sessions of %s team member in Zendesk", resource.DisplayName)),
+		ent.WithGrantableTo(resourceTypeTeam),
+	))
+
 	return rv, "", nil, nil
 }
 
 func (t *teamResourceType) List(ctx context.Context, parentID *v2.ResourceId, pToken *pagination.Token) ([]*v2.Resource, string, annotations.Annotations, error) {
-	var (
-		pageToken int
-		err       error
-		ret       []*v2.Resource
-	)
-	if pToken.Token != "" {
-		pageToken, err = strconv.Atoi(pToken.Token)
+	var ret []*v2.Resource
+
+	if pToken.Token == "" {
+		// Users cached by an earlier sync are dropped when the team members are listed again.
+		t.users.reset()
+
+		// The SSO status of team members is read once per sync.
+		err := t.traits.loadSecuritySettings(ctx, t.client)
 		if err != nil {
 			return nil, "", nil, err
 		}
 	}
 
-	users, nextPageToken, err := t.client.ListUsers(ctx, pageToken)
+	users, identities, nextPageToken, err := t.listUsers(ctx, pToken.Token)
 	if err != nil {
 		return nil, "", nil, err
 	}
 
 	for _, user := range users {
 		userCopy := user
-		if !isValidTeamMember(&userCopy) {
+		if !t.members.includes(&userCopy) {
 			continue
 		}
-		res, err := getTeamResource(&userCopy, resourceTypeTeam)
+
+		userIdentities := identities[user.ID]
+
+		var sessions []client.Session
+		if t.syncSessions {
+			sessions, err = t.client.ListUserSessions(ctx, user.ID)
+			if err != nil {
+				return nil, "", nil, fmt.Errorf("zendesk-connector: failed to list sessions of team member %d: %w", user.ID, err)
+			}
+		}
+
+		userFields, err := t.customFields.userFields(ctx, t.client, user.UserFields)
+		if err != nil {
+			return nil, "", nil, err
+		}
+
+		res, err := getTeamResource(&userCopy, userIdentities, sessions, userFields, t.traits, resourceTypeTeam)
 		if err != nil {
 			return nil, "", nil, err
 		}
@@ -92,14 +134,113 @@ func (t *teamResourceType) List(ctx context.Context, parentID *v2.ResourceId, pT
 	return ret, nextPageToken, nil, nil
 }
 
-// Grants always returns an empty slice for teams since they don't have any entitlements.
-func (o *teamResourceType) Grants(ctx context.Context, resource *v2.Resource, pToken *pagination.Token) ([]*v2.Grant, string, annotations.Annotations, error) {
-	return nil, "", nil, nil
+// listUsers returns a page of users along with their identities. Users matching the user query are fetched through
+// the search export API, which pages with a cursor and is narrowed to the team member roles. Search results do not
+// side-load identities, so the team members found are fetched again with show_many along with their identities.
+func (t *teamResourceType) listUsers(ctx context.Context, token string) ([]zendesk.User, map[int64][]client.UserIdentity, string, error) {
+	if t.userQuery != "" {
+		query := searchQuery(t.userQuery, "role", t.members.roleNames())
+		found, nextPageToken, err := t.client.SearchUsers(ctx, query, token)
+		if err != nil {
+			return nil, nil, "", fmt.Errorf("zendesk-connector: failed to search users: %w", err)
+		}
+
+		userIDs := make([]int64, 0, len(found))
+		for _, user := range found {
+			userCopy := user
+			if t.members.includes(&userCopy) {
+				userIDs = append(userIDs, user.ID)
+			}
+		}
+
+		users := make([]zendesk.User, 0, len(userIDs))
+		identities := make(map[int64][]client.UserIdentity, len(userIDs))
+		for start := 0; start < len(userIDs); start += showManyUsersLimit {
+			end := min(start+showManyUsersLimit, len(userIDs))
+
+			fetched, fetchedIdentities, err := t.client.GetManyUsersWithIdentities(ctx, userIDs[start:end])
+			if err != nil {
+				return nil, nil, "", fmt.Errorf("zendesk-connector: failed to get team members: %w", err)
+			}
+			users = append(users, fetched...)
+			maps.Copy(identities, fetchedIdentities)
+		}
+		t.users.add(users)
+
+		return users, identities, nextPageToken, nil
+	}
+
+	var pageToken int
+	if token != "" {
+		var err error
+		pageToken, err = strconv.Atoi(token)
+		if err != nil {
+			return nil, nil, "", err
+		}
+	}
+
+	users, identities, nextPageToken, err := t.client.ListUsersWithIdentities(ctx, pageToken)
+	if err != nil {
+		return nil, nil, "", err
+	}
+	t.users.addPage(pageToken, users, nextPageToken)
+
+	return users, identities, nextPageToken, nil
+}
+
+// Grants returns the session grant of a team member that has active sessions.
+func (t *teamResourceType) Grants(ctx context.Context, resource *v2.Resource, pToken *pagination.Token) ([]*v2.Grant, string, annotations.Annotations, error) {
+	userTrait, err := rs.GetUserTrait(resource)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	sessionCount, ok := rs.GetProfileInt64Value(userTrait.GetProfile(), profileSessionCount)
+	if !ok || sessionCount == 0 {
+		return nil, "", nil, nil
+	}
+
+	return []*v2.Grant{grant.NewGrant(resource, teamSessionEntitlement, resource.Id)}, "", nil, nil
+}
+
+func (t *teamResourceType) Grant(ctx context.Context, principal *v2.Resource, entitlement *v2.Entitlement) (annotations.Annotations, error) {
+	return nil, fmt.Errorf("zendesk-connector: team member entitlements can not be granted")
+}
+
+// Revoke signs a team member out of every active session when the session entitlement is revoked.
+func (t *teamResourceType) Revoke(ctx context.Context, g *v2.Grant) (annotations.Annotations, error) {
+	l := ctxzap.Extract(ctx)
+
+	if g.Entitlement.Slug != teamSessionEntitlement {
+		return nil, fmt.Errorf("zendesk-connector: only the session entitlement of a team member can be revoked")
+	}
+
+	userID, err := strconv.ParseInt(g.Entitlement.Resource.Id.Resource, 10, 64)
+	if err != nil {
+		return nil, err
+	}
+
+	err = t.client.DeleteUserSessions(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("zendesk-connector: failed to sign out team member: %w", err)
+	}
+
+	l.Warn("Sessions have been deleted.",
+		zap.Int64("UserID", userID),
+	)
+
+	return nil, nil
 }
 
-func teamBuilder(c *client.ZendeskClient) *teamResourceType {
+func teamBuilder(c *client.ZendeskClient, users *userDirectory, traits *userTraitConfig, members *teamMemberFilter, customFields *customFieldConfig, userQuery string, syncSessions bool) *teamResourceType {
 	return &teamResourceType{
 		resourceType: resourceTypeTeam,
 		client:       c,
+		users:        users,
+		traits:       traits,
+		members:      members,
+		customFields: customFields,
+		userQuery:    userQuery,
+		syncSessions: syncSessions,
 	}
 }
@@ -0,0 +1,80 @@
+package connector
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/nukosuke/go-zendesk/zendesk"
+)
+
+// defaultTeamMemberRoles are the Zendesk roles synced as team members unless configured otherwise.
+var defaultTeamMemberRoles = []string{teamRoleAgent, teamRoleAdmin}
+
+// teamMemberFilter holds the connector settings deciding which users are synced as team members.
+type teamMemberFilter struct {
+	roles            map[string]struct{}
+	excludeSuspended bool
+	excludeInactive  bool
+	customRoleIDs    map[int64]struct{}
+}
+
+func newTeamMemberFilter(roles []string, excludeSuspended bool, excludeInactive bool, customRoleIDs []string) (*teamMemberFilter, error) {
+	if len(roles) == 0 {
+		roles = defaultTeamMemberRoles
+	}
+
+	f := &teamMemberFilter{
+		roles:            make(map[string]struct{}, len(roles)),
+		excludeSuspended: excludeSuspended,
+		excludeInactive:  excludeInactive,
+		customRoleIDs:    make(map[int64]struct{}, len(customRoleIDs)),
+	}
+
+	for _, role := range roles {
+		f.roles[strings.ToLower(strings.TrimSpace(role))] = struct{}{}
+	}
+
+	for _, id := range customRoleIDs {
+		customRoleID, err := strconv.ParseInt(strings.TrimSpace(id), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("zendesk-connector: invalid custom role id %q: %w", id, err)
+		}
+		f.customRoleIDs[customRoleID] = struct{}{}
+	}
+
+	return f, nil
+}
+
+// includes reports whether a user is synced as a team member.
+// Suspended and inactive users that are not excluded keep a disabled status on their resource.
+func (f *teamMemberFilter) includes(user *zendesk.User) bool {
+	if _, ok := f.roles[user.Role]; !ok {
+		return false
+	}
+	if user.Suspended && f.excludeSuspended {
+		return false
+	}
+	if !user.Active && f.excludeInactive {
+		return false
+	}
+	if len(f.customRoleIDs) > 0 {
+		if _, ok := f.customRoleIDs[user.CustomRoleID]; !ok {
+			return false
+		}
+	}
+
+	return true
+}
+
+// roleNames returns the Zendesk roles synced as team members, sorted by name.
+func (f *teamMemberFilter) roleNames() []string {
+	names := make([]string, 0, len(f.roles))
+	for role := range f.roles {
+		names = append(names, role)
+	}
+	slices.Sort(names)
+
+	return names
+}
@@ -0,0 +1,184 @@
+package connector
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/conductorone/baton-zendesk/pkg/client"
+	"github.com/nukosuke/go-zendesk/zendesk"
+)
+
+// showManyUsersLimit is the most users Zendesk returns from a single show_many request.
+const showManyUsersLimit = 100
+
+// userDirectory caches the team members of the account for a sync, shared by every syncer. Team members are filled
+// lazily, from the pages of users listed and from show_many batches of the users looked up by ID. Other users are
+// returned to the calls looking them up but not cached, so the cache is bounded by the number of team members.
+// Provisioning writes invalidate the users they change.
+type userDirectory struct {
+	client  *client.ZendeskClient
+	members *teamMemberFilter
+
+	mu    sync.Mutex
+	users map[int64]zendesk.User
+	pages map[int]userPage
+}
+
+// userPage is a page of the account's users, as listed by ListUsers, holding the IDs of its team members.
+type userPage struct {
+	userIDs       []int64
+	nextPageToken string
+}
+
+func newUserDirectory(c *client.ZendeskClient, members *teamMemberFilter) *userDirectory {
+	return &userDirectory{
+		client:  c,
+		members: members,
+		users:   make(map[int64]zendesk.User),
+		pages:   make(map[int]userPage),
+	}
+}
+
+// reset drops every cached user, so that a new sync sees the current state of the account.
+func (d *userDirectory) reset() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.users = make(map[int64]zendesk.User)
+	d.pages = make(map[int]userPage)
+}
+
+// invalidate drops the cached users. Cached pages keep their IDs, and fetch the users again when listed.
+func (d *userDirectory) invalidate(userIDs ...int64) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for _, userID := range userIDs {
+		delete(d.users, userID)
+	}
+}
+
+// add caches the team members among users fetched by another call.
+func (d *userDirectory) add(users []zendesk.User) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.addLocked(users)
+}
+
+func (d *userDirectory) addLocked(users []zendesk.User) []int64 {
+	userIDs := make([]int64, 0, len(users))
+	for _, user := range users {
+		userCopy := user
+		if !d.members.includes(&userCopy) {
+			continue
+		}
+		d.users[user.ID] = user
+		userIDs = append(userIDs, user.ID)
+	}
+
+	return userIDs
+}
+
+// addPage caches a page of the account's users listed by another call.
+func (d *userDirectory) addPage(pageToken int, users []zendesk.User, nextPageToken string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.pages[pageToken] = userPage{userIDs: d.addLocked(users), nextPageToken: nextPageToken}
+}
+
+// listPage returns the team members of a page of the account's users, listing the page only the first time it is
+// asked for. Team members invalidated since are fetched again by ID.
+func (d *userDirectory) listPage(ctx context.Context, pageToken int) ([]zendesk.User, string, error) {
+	d.mu.Lock()
+	page, ok := d.pages[pageToken]
+	d.mu.Unlock()
+	if ok {
+		found, err := d.getMany(ctx, page.userIDs)
+		if err != nil {
+			return nil, "", err
+		}
+
+		users := make([]zendesk.User, 0, len(page.userIDs))
+		for _, userID := range page.userIDs {
+			if user, ok := found[userID]; ok {
+				users = append(users, user)
+			}
+		}
+
+		return users, page.nextPageToken, nil
+	}
+
+	listed, nextPageToken, err := d.client.ListUsers(ctx, pageToken)
+	if err != nil {
+		return nil, "", err
+	}
+
+	users := make([]zendesk.User, 0, len(listed))
+	for _, user := range listed {
+		userCopy := user
+		if d.members.includes(&userCopy) {
+			users = append(users, user)
+		}
+	}
+	d.addPage(pageToken, users, nextPageToken)
+
+	return users, nextPageToken, nil
+}
+
+// get returns a user, fetching it if it is not cached.
+func (d *userDirectory) get(ctx context.Context, userID int64) (zendesk.User, error) {
+	users, err := d.getMany(ctx, []int64{userID})
+	if err != nil {
+		return zendesk.User{}, err
+	}
+
+	user, ok := users[userID]
+	if !ok {
+		// show_many leaves out missing users, so the user is fetched on its own to report why.
+		return d.client.GetUser(ctx, userID)
+	}
+
+	return user, nil
+}
+
+// getMany returns the users with the given IDs, keyed by ID, fetching the ones that are not cached
+// with show_many. Users that no longer exist are left out.
+func (d *userDirectory) getMany(ctx context.Context, userIDs []int64) (map[int64]zendesk.User, error) {
+	users := make(map[int64]zendesk.User, len(userIDs))
+	var missing []int64
+	seen := make(map[int64]struct{}, len(userIDs))
+
+	d.mu.Lock()
+	for _, userID := range userIDs {
+		if _, ok := seen[userID]; ok {
+			continue
+		}
+		seen[userID] = struct{}{}
+
+		if user, ok := d.users[userID]; ok {
+			users[userID] = user
+		} else {
+			missing = append(missing, userID)
+		}
+	}
+	d.mu.Unlock()
+
+	for start := 0; start < len(missing); start += showManyUsersLimit {
+		end := min(start+showManyUsersLimit, len(missing))
+
+		fetched, err := d.client.GetManyUsers(ctx, missing[start:end])
+		if err != nil {
+			return nil, fmt.Errorf("zendesk-connector: failed to get users: %w", err)
+		}
+		d.add(fetched)
+
+		for _, user := range fetched {
+			users[user.ID] = user
+		}
+	}
+
+	return users, nil
+}
@@ -0,0 +1,154 @@
+package connector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	rs "github.com/conductorone/baton-sdk/pkg/types/resource"
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"github.com/nukosuke/go-zendesk/zendesk"
+	"go.uber.org/zap"
+
+	"github.com/conductorone/baton-zendesk/pkg/client"
+)
+
+// userTraitConfig holds the connector settings that shape the user trait of team members.
+type userTraitConfig struct {
+	// ssoEnforced is the configured SSO status, used when the security settings of the account cannot be read.
+	ssoEnforced bool
+
+	mu         sync.Mutex
+	agentSSO   bool
+	ssoFromAPI bool
+
+	serviceAccountPatterns      []*regexp.Regexp
+	serviceAccountTags          map[string]struct{}
+	serviceAccountNeverLoggedIn bool
+	apiOnlyPatterns             []*regexp.Regexp
+}
+
+func newUserTraitConfig(ssoEnforced bool, patterns []string, tags []string, neverLoggedIn bool, apiOnlyPatterns []string) (*userTraitConfig, error) {
+	c := &userTraitConfig{
+		ssoEnforced:                 ssoEnforced,
+		serviceAccountTags:          make(map[string]struct{}, len(tags)),
+		serviceAccountNeverLoggedIn: neverLoggedIn,
+	}
+
+	for _, pattern := range patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("zendesk-connector: invalid service account pattern %q: %w", pattern, err)
+		}
+		c.serviceAccountPatterns = append(c.serviceAccountPatterns, re)
+	}
+
+	for _, pattern := range apiOnlyPatterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("zendesk-connector: invalid API-only pattern %q: %w", pattern, err)
+		}
+		c.apiOnlyPatterns = append(c.apiOnlyPatterns, re)
+	}
+
+	for _, tag := range tags {
+		c.serviceAccountTags[strings.ToLower(tag)] = struct{}{}
+	}
+
+	return c, nil
+}
+
+// loadSecuritySettings reads whether team members sign in through SSO from the security settings of the account.
+// The configured SSO status is used instead when the settings cannot be read, for example on plans without SSO
+// or when the connector lacks the admin role.
+func (c *userTraitConfig) loadSecuritySettings(ctx context.Context, z *client.ZendeskClient) error {
+	settings, err := z.GetAccountSecuritySettings(ctx)
+	if err != nil {
+		if ctx.Err() != nil {
+			return err
+		}
+
+		l := ctxzap.Extract(ctx)
+		msg := "zendesk-connector: account security settings unavailable, using the configured SSO status"
+		if errors.Is(err, client.ErrSecuritySettingsUnavailable) {
+			l.Debug(msg, zap.Bool("sso_enforced", c.ssoEnforced))
+		} else {
+			l.Warn(msg, zap.Bool("sso_enforced", c.ssoEnforced), zap.Error(err))
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.ssoFromAPI = err == nil
+	c.agentSSO = settings.AgentSSO
+
+	return nil
+}
+
+// ssoEnabled reports whether team members sign in through SSO.
+func (c *userTraitConfig) ssoEnabled() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.ssoFromAPI {
+		return c.agentSSO
+	}
+
+	return c.ssoEnforced
+}
+
+// securityTraitOptions returns the MFA and SSO status of a user.
+func (c *userTraitConfig) securityTraitOptions(user *zendesk.User) []rs.UserTraitOption {
+	return []rs.UserTraitOption{
+		rs.WithMFAStatus(&v2.UserTrait_MFAStatus{MfaEnabled: user.TwoFactorAuthEnabled}),
+		rs.WithSSOStatus(&v2.UserTrait_SSOStatus{SsoEnabled: c.ssoEnabled()}),
+	}
+}
+
+// accountType classifies a user as a human or a service account, and returns why it was classified as a service account.
+func (c *userTraitConfig) accountType(user *zendesk.User) (v2.UserTrait_AccountType, string) {
+	for _, re := range c.serviceAccountPatterns {
+		if re.MatchString(user.Email) || re.MatchString(user.Name) {
+			return v2.UserTrait_ACCOUNT_TYPE_SERVICE, fmt.Sprintf("pattern:%s", re.String())
+		}
+	}
+
+	for _, tag := range user.Tags {
+		if _, ok := c.serviceAccountTags[strings.ToLower(tag)]; ok {
+			return v2.UserTrait_ACCOUNT_TYPE_SERVICE, fmt.Sprintf("tag:%s", tag)
+		}
+	}
+
+	if user.Shared || user.SharedAgent {
+		return v2.UserTrait_ACCOUNT_TYPE_SERVICE, "shared"
+	}
+
+	// Users that never signed in to the agent interface may only authenticate through the API, but so do
+	// invited team members that have not signed in yet, so their email or name has to look like an integration too.
+	if c.serviceAccountNeverLoggedIn && user.LastLoginAt.IsZero() {
+		for _, re := range c.apiOnlyPatterns {
+			if re.MatchString(user.Email) || re.MatchString(user.Name) {
+				return v2.UserTrait_ACCOUNT_TYPE_SERVICE, "api_only"
+			}
+		}
+	}
+
+	return v2.UserTrait_ACCOUNT_TYPE_HUMAN, ""
+}
+
+// accountTypeTraitOptions returns the account type of a user, and the profile fields recording why it is a service account.
+func (c *userTraitConfig) accountTypeTraitOptions(user *zendesk.User) ([]rs.UserTraitOption, map[string]interface{}) {
+	accountType, reason := c.accountType(user)
+
+	var profile map[string]interface{}
+	if reason != "" {
+		profile = map[string]interface{}{"service_account_reason": reason}
+	}
+
+	return []rs.UserTraitOption{rs.WithAccountType(accountType)}, profile
+}
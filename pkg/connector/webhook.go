@@ -0,0 +1,64 @@
+package connector
+
+import (
+	"context"
+	"fmt"
+
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	"github.com/conductorone/baton-zendesk/pkg/client"
+)
+
+type webhookResourceType struct {
+	resourceType *v2.ResourceType
+	client       *client.ZendeskClient
+}
+
+func (w *webhookResourceType) ResourceType(_ context.Context) *v2.ResourceType {
+	return w.resourceType
+}
+
+// List returns all the webhooks of the account as app resources. The signing secret of each webhook is
+// fetched to tell whether it signs its requests, since listings leave it out.
+func (w *webhookResourceType) List(ctx context.Context, parentId *v2.ResourceId, pToken *pagination.Token) ([]*v2.Resource, string, annotations.Annotations, error) {
+	var ret []*v2.Resource
+	webhooks, nextPageToken, err := w.client.ListWebhooks(ctx, pToken.Token)
+	if err != nil {
+		return nil, "", nil, fmt.Errorf("zendesk-connector: failed to list webhooks: %w", err)
+	}
+
+	for _, webhook := range webhooks {
+		webhookCopy := webhook
+		signed, err := w.client.WebhookHasSigningSecret(ctx, webhook.ID)
+		if err != nil {
+			return nil, "", nil, fmt.Errorf("zendesk-connector: failed to fetch signing secret of webhook %s: %w", webhook.ID, err)
+		}
+
+		res, err := getWebhookResource(&webhookCopy, signed, resourceTypeWebhook)
+		if err != nil {
+			return nil, "", nil, err
+		}
+
+		ret = append(ret, res)
+	}
+
+	return ret, nextPageToken, nil, nil
+}
+
+// Entitlements always returns an empty slice for webhooks.
+func (w *webhookResourceType) Entitlements(_ context.Context, _ *v2.Resource, _ *pagination.Token) ([]*v2.Entitlement, string, annotations.Annotations, error) {
+	return nil, "", nil, nil
+}
+
+// Grants always returns an empty slice for webhooks.
+func (w *webhookResourceType) Grants(_ context.Context, _ *v2.Resource, _ *pagination.Token) ([]*v2.Grant, string, annotations.Annotations, error) {
+	return nil, "", nil, nil
+}
+
+func webhookBuilder(c *client.ZendeskClient) *webhookResourceType {
+	return &webhookResourceType{
+		resourceType: resourceTypeWebhook,
+		client:       c,
+	}
+}